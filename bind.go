@@ -0,0 +1,349 @@
+package web
+
+import (
+	"encoding"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMultipartMemory 解析 multipart 表单时最多放在内存里面的字节数，超过的部分写到临时文件
+const defaultMultipartMemory = 32 << 20
+
+// bindSources Bind 支持的 tag，排在后面的来源优先级更高
+var bindSources = []string{"form", "query", "header", "path"}
+
+// BindError 绑定失败的字段，Bind 会检查完所有的字段再返回
+type BindError struct {
+	Fields []*ValueError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return "web: 绑定参数失败: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap 返回每个字段的错误，Go 1.20 开始 errors.Is 和 errors.As 会逐个检查
+func (e *BindError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// asMaxBytesError 判断 err 是不是因为请求体超过了大小限制。
+// 解析表单的时候超过限制，错误包在 BindError 的字段里面，低版本的 errors.As 找不到，所以逐个检查
+func asMaxBytesError(err error) (*http.MaxBytesError, bool) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return maxBytesErr, true
+	}
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		for _, f := range bindErr.Fields {
+			if errors.As(f.Err, &maxBytesErr) {
+				return maxBytesErr, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Bind 把请求绑定到 val 上，val 必须是结构体指针。
+// 先根据 Content-Type 解析请求体：JSON 和 XML 直接解码到 val 上，表单交给 form tag 处理，
+// 请求体不是表单的时候 form tag 会被忽略，需要从查询参数取值的字段用 query tag；
+// 然后按照字段上的 tag 从请求里面取值，例如：
+//
+//	type UpdateUserReq struct {
+//		ID     int64  `path:"id"`
+//		Page   int    `query:"page"`
+//		Tenant string `header:"X-Tenant"`
+//		Name   string `form:"name" json:"name"`
+//	}
+//
+// 一个字段可以有多个 tag，同时存在的时候 path > header > query > form > 请求体。
+// 请求里面没有的字段保持原样，所以可以预先在 val 里面设置默认值。
+// 字段转换失败的时候返回 *BindError，包含了所有失败的字段。
+// 绑定成功之后会按照 validate tag 校验，失败的时候返回 *ValidationError，见 Validate
+func (c *Context) Bind(val any) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("web: Bind 的参数必须是结构体指针")
+	}
+	isForm, err := c.bindBody(val)
+	if err != nil {
+		return err
+	}
+	var fieldErrs []*ValueError
+	c.bindStruct(rv.Elem(), isForm, &fieldErrs)
+	if len(fieldErrs) > 0 {
+		return &BindError{Fields: fieldErrs}
+	}
+	return Validate(val)
+}
+
+// BindOrReject 调用 Bind，失败的时候交给 BindErrorHandlerOption 设置的处理逻辑，并返回 false。
+// 默认响应 400，内容是 application/problem+json 格式的错误列表。handler 里面一般这么用：
+//
+//	var req CreateUserReq
+//	if !ctx.BindOrReject(&req) {
+//		return
+//	}
+func (c *Context) BindOrReject(val any) bool {
+	err := c.Bind(val)
+	if err == nil {
+		return true
+	}
+	handler := c.bindErrorHandler
+	if handler == nil {
+		handler = defaultBindErrorHandler
+	}
+	handler(c, err)
+	return false
+}
+
+// BindErrorHandlerOption 设置 BindOrReject 失败时的处理逻辑。
+// err 可能是 *BindError、*ValidationError，或者解析请求体时的错误
+func BindErrorHandlerOption(handler func(ctx *Context, err error)) ServerOption {
+	return func(server *HttpServer) {
+		server.bindErrorHandler = handler
+	}
+}
+
+// problem RFC 7807 定义的错误响应
+type problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+func defaultBindErrorHandler(ctx *Context, err error) {
+	p := problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusBadRequest),
+		Status: http.StatusBadRequest,
+	}
+	var (
+		bindErr     *BindError
+		validateErr *ValidationError
+	)
+	maxBytesErr, tooLarge := asMaxBytesError(err)
+	switch {
+	case tooLarge:
+		p.Title = http.StatusText(http.StatusRequestEntityTooLarge)
+		p.Status = http.StatusRequestEntityTooLarge
+		p.Detail = fmt.Sprintf("请求体不能超过 %d 字节", maxBytesErr.Limit)
+	case errors.As(err, &bindErr):
+		p.Detail = "请求参数格式错误"
+		for _, f := range bindErr.Fields {
+			p.Errors = append(p.Errors, FieldError{
+				Field:   f.Key,
+				Rule:    "type",
+				Message: fmt.Sprintf("值 %q 不合法: %v", f.Value, f.Err),
+			})
+		}
+	case errors.As(err, &validateErr):
+		p.Detail = "请求参数校验失败"
+		p.Errors = validateErr.Errors
+	default:
+		p.Detail = err.Error()
+	}
+	_ = ctx.RespJSON(p.Status, p)
+	ctx.Header().Set("Content-Type", "application/problem+json")
+}
+
+// bindBody 解析请求体，返回请求体是不是表单
+func (c *Context) bindBody(val any) (bool, error) {
+	if c.Req.Body == nil || c.Req.Body == http.NoBody || c.Req.ContentLength == 0 {
+		return false, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		return true, nil
+	case MIMEJSON, MIMEXML, "text/xml":
+	default:
+		return false, nil
+	}
+	body, err := c.Body()
+	if err != nil {
+		return false, err
+	}
+	if mediaType == MIMEJSON {
+		err = c.decodeJSON(body, val)
+	} else {
+		err = xml.Unmarshal(body, val)
+	}
+	if err != nil {
+		return false, fmt.Errorf("web: 解析请求体失败: %w", err)
+	}
+	return false, nil
+}
+
+func (c *Context) bindStruct(v reflect.Value, isForm bool, fieldErrs *[]*ValueError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if field.Anonymous && fv.Kind() == reflect.Struct {
+			c.bindStruct(fv, isForm, fieldErrs)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		for _, source := range bindSources {
+			key, ok := field.Tag.Lookup(source)
+			if !ok || key == "" || key == "-" {
+				continue
+			}
+			vals, err := c.bindValues(source, key, isForm)
+			if err != nil {
+				*fieldErrs = append(*fieldErrs, &ValueError{Source: source, Key: key, Err: err})
+				continue
+			}
+			if len(vals) == 0 {
+				continue
+			}
+			if bad, err := setField(fv, vals); err != nil {
+				*fieldErrs = append(*fieldErrs, &ValueError{Source: source, Key: key, Value: bad, Err: err})
+			}
+		}
+	}
+}
+
+func (c *Context) bindValues(source string, key string, isForm bool) ([]string, error) {
+	switch source {
+	case "path":
+		if val, ok := c.PathParams[key]; ok {
+			return []string{val}, nil
+		}
+		return nil, nil
+	case "query":
+		return c.query()[key], nil
+	case "header":
+		return c.Req.Header.Values(key), nil
+	default:
+		// 请求体不是表单的时候 form tag 不生效，避免查询参数覆盖 JSON、XML 里面的值
+		if !isForm {
+			return nil, nil
+		}
+		if err := c.parseForm(); err != nil {
+			return nil, err
+		}
+		return c.Req.Form[key], nil
+	}
+}
+
+func (c *Context) parseForm() error {
+	if c.Req.Form != nil {
+		return nil
+	}
+	// 请求体已经被 Body 读过了，从缓存里面解析
+	if c.bodyCached {
+		c.resetBody()
+	}
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		err = c.Req.ParseMultipartForm(defaultMultipartMemory)
+	} else {
+		err = c.Req.ParseForm()
+	}
+	if err != nil {
+		c.checkBodyTooLarge(err)
+	}
+	return err
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// setField 把 vals 转换之后设置到 fv 上，失败的时候返回转换失败的值
+func setField(fv reflect.Value, vals []string) (string, error) {
+	if fv.Kind() == reflect.Slice && !fv.Type().Implements(textUnmarshalerType) &&
+		fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), val); err != nil {
+				return val, err
+			}
+		}
+		fv.Set(slice)
+		return "", nil
+	}
+	if err := setValue(fv, vals[0]); err != nil {
+		return vals[0], err
+	}
+	return "", nil
+}
+
+func setValue(fv reflect.Value, val string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), val); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return unwrapNumError(err)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return unwrapNumError(err)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return unwrapNumError(err)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return unwrapNumError(err)
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		// []byte
+		fv.SetBytes([]byte(val))
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", fv.Type())
+	}
+	return nil
+}
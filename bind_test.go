@@ -0,0 +1,150 @@
+package web
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPage struct {
+	Page int `query:"page"`
+	Size int `query:"size"`
+}
+
+type bindUserReq struct {
+	bindPage
+	ID       int64         `path:"id"`
+	Tenant   string        `header:"X-Tenant"`
+	Name     string        `json:"name" xml:"name" form:"name"`
+	Age      *uint8        `json:"age" xml:"age" form:"age"`
+	Tags     []string      `query:"tag"`
+	Timeout  time.Duration `query:"timeout"`
+	Since    time.Time     `query:"since"`
+	Override string        `json:"override" query:"override"`
+	internal string        `query:"internal"`
+}
+
+func TestContext_Bind(t *testing.T) {
+	age := uint8(18)
+	since := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	multipartBody := &bytes.Buffer{}
+	mw := multipart.NewWriter(multipartBody)
+	require.NoError(t, mw.WriteField("name", "Tom"))
+	require.NoError(t, mw.WriteField("age", "18"))
+	require.NoError(t, mw.Close())
+
+	testCases := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		want        bindUserReq
+		wantErr     string
+	}{
+		{
+			name:        "json",
+			query:       "page=2&tag=a&tag=b&timeout=1m&since=2023-01-02T03:04:05Z&override=query&internal=x",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"Tom","age":18,"override":"body"}`,
+			want: bindUserReq{
+				bindPage: bindPage{Page: 2, Size: 10},
+				ID:       12, Tenant: "t1", Name: "Tom", Age: &age,
+				Tags: []string{"a", "b"}, Timeout: time.Minute, Since: since,
+				Override: "query",
+			},
+		},
+		{
+			// 请求体不是表单，form tag 不会读取查询参数
+			name:        "json ignores form tag",
+			query:       "name=query&age=20",
+			contentType: "application/json",
+			body:        `{"name":"Tom","age":18}`,
+			want: bindUserReq{
+				bindPage: bindPage{Size: 10},
+				ID:       12, Tenant: "t1", Name: "Tom", Age: &age,
+			},
+		},
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body:        `<bindUserReq><name>Tom</name><age>18</age></bindUserReq>`,
+			want: bindUserReq{
+				bindPage: bindPage{Size: 10},
+				ID:       12, Tenant: "t1", Name: "Tom", Age: &age,
+			},
+		},
+		{
+			name:        "form",
+			query:       "page=3",
+			contentType: "application/x-www-form-urlencoded",
+			body:        url.Values{"name": []string{"Tom"}, "age": []string{"18"}}.Encode(),
+			want: bindUserReq{
+				bindPage: bindPage{Page: 3, Size: 10},
+				ID:       12, Tenant: "t1", Name: "Tom", Age: &age,
+			},
+		},
+		{
+			name:        "multipart",
+			contentType: mw.FormDataContentType(),
+			body:        multipartBody.String(),
+			want: bindUserReq{
+				bindPage: bindPage{Size: 10},
+				ID:       12, Tenant: "t1", Name: "Tom", Age: &age,
+			},
+		},
+		{
+			// 所有失败的字段一起返回
+			name:        "field errors",
+			query:       "page=abc&timeout=1x",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "age=300",
+			wantErr: `web: 绑定参数失败: web: query 参数 page 的值 "abc" 不合法: invalid syntax; ` +
+				`web: form 参数 age 的值 "300" 不合法: value out of range; ` +
+				`web: query 参数 timeout 的值 "1x" 不合法: time: unknown unit "x" in duration "1x"`,
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			body:        `{"name":`,
+			wantErr:     "web: 解析请求体失败: unexpected EOF",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/12?"+tc.query, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("X-Tenant", "t1")
+			ctx := &Context{Req: req, PathParams: map[string]string{"id": "12"}}
+			// 请求里面没有的字段保持默认值
+			val := bindUserReq{bindPage: bindPage{Size: 10}}
+			err := ctx.Bind(&val)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, val)
+		})
+	}
+}
+
+func TestContext_BindErrors(t *testing.T) {
+	ctx := &Context{Req: httptest.NewRequest(http.MethodGet, "/?page=a", nil)}
+	var val bindPage
+	assert.EqualError(t, ctx.Bind(val), "web: Bind 的参数必须是结构体指针")
+
+	err := ctx.Bind(&val)
+	var bindErr *BindError
+	require.ErrorAs(t, err, &bindErr)
+	require.Len(t, bindErr.Fields, 1)
+	assert.Equal(t, "query", bindErr.Fields[0].Source)
+	assert.Equal(t, "page", bindErr.Fields[0].Key)
+	assert.Equal(t, "a", bindErr.Fields[0].Value)
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// MaxBodyBytesOption 限制请求体的大小，超过之后读取请求体会失败，并且响应 413。
+// 默认不限制，单个路由可以通过 MaxBodyBytes 覆盖
+func MaxBodyBytesOption(n int64) ServerOption {
+	return func(server *HttpServer) {
+		server.maxBodyBytes = n
+	}
+}
+
+// StrictJSONOption BindJSON 和 Bind 解码 JSON 的时候不允许出现结构体里面没有的字段
+func StrictJSONOption() ServerOption {
+	return func(server *HttpServer) {
+		server.strictJSON = true
+	}
+}
+
+// MaxBodyBytes 返回限制请求体大小的 middleware，用于给单个路由或者分组设置不同于整个 server 的限制，
+// 例如上传文件的路由：
+//
+//	server.AddRoute(http.MethodPost, "/upload", upload, web.MaxBodyBytes(100<<20))
+func MaxBodyBytes(n int64) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			ctx.SetMaxBodyBytes(n)
+			next(ctx)
+		}
+	}
+}
+
+// SetMaxBodyBytes 限制请求体的大小，会覆盖之前设置的限制。需要在读取请求体之前调用
+func (c *Context) SetMaxBodyBytes(n int64) {
+	if c.Req.Body == nil || c.bodyCached {
+		return
+	}
+	if c.rawBody == nil {
+		c.rawBody = c.Req.Body
+	}
+	c.Req.Body = http.MaxBytesReader(c.Resp, c.rawBody, n)
+}
+
+// Body 读取整个请求体，结果会被缓存起来，所以 middleware 和 handler 都可以调用。
+// 读取之后 Req.Body 会被替换成缓存的内容，直接读 Req.Body 也能拿到完整的数据。
+// 请求体超过限制的时候返回的 error 包含 *http.MaxBytesError，并且把响应设置成 413
+func (c *Context) Body() ([]byte, error) {
+	if c.bodyCached {
+		c.resetBody()
+		return c.body, nil
+	}
+	if c.Req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(c.Req.Body)
+	if err != nil {
+		c.checkBodyTooLarge(err)
+		return nil, err
+	}
+	_ = c.Req.Body.Close()
+	c.body = body
+	c.bodyCached = true
+	c.resetBody()
+	return body, nil
+}
+
+// checkBodyTooLarge 读取请求体的错误是因为超过了大小限制的时候，把响应设置成 413
+func (c *Context) checkBodyTooLarge(err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.RespStatusCode = http.StatusRequestEntityTooLarge
+		c.RespData = []byte("REQUEST ENTITY TOO LARGE")
+	}
+}
+
+// resetBody 让 Req.Body 重新从缓存的开头读
+func (c *Context) resetBody() {
+	c.Req.Body = io.NopCloser(bytes.NewReader(c.body))
+}
+
+// decodeJSON 解码 JSON，请求体里面除了一个 JSON 值之外还有别的内容的时候返回 error
+func (c *Context) decodeJSON(data []byte, val any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if c.strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(val); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("web: JSON 后面有多余的内容")
+	}
+	return nil
+}
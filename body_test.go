@@ -0,0 +1,230 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContext_Body(t *testing.T) {
+	type user struct {
+		Name string `json:"name" form:"name"`
+	}
+	var logged string
+	s := NewHttpServer(MiddlewaresOption([]Middleware{
+		func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				body, err := ctx.Body()
+				require.NoError(t, err)
+				logged = string(body)
+				next(ctx)
+			}
+		},
+	}))
+	s.AddRoute(http.MethodPost, "/json", func(ctx *Context) {
+		// middleware 读过之后 handler 依旧可以重复读取
+		var u1, u2 user
+		require.NoError(t, ctx.BindJSON(&u1))
+		require.NoError(t, ctx.BindJSON(&u2))
+		raw, err := io.ReadAll(ctx.Req.Body)
+		require.NoError(t, err)
+		ctx.RespData = []byte(u1.Name + u2.Name + string(raw))
+	})
+	s.AddRoute(http.MethodPost, "/form", func(ctx *Context) {
+		var u user
+		require.NoError(t, ctx.Bind(&u))
+		ctx.RespData = []byte(u.Name)
+	})
+
+	testCases := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantBody    string
+	}{
+		{
+			name:        "json",
+			path:        "/json",
+			contentType: "application/json",
+			body:        `{"name":"Tom"}`,
+			wantBody:    `TomTom{"name":"Tom"}`,
+		},
+		{
+			name:        "form",
+			path:        "/form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=Jerry",
+			wantBody:    "Jerry",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.body, logged)
+		})
+	}
+}
+
+func TestHttpServer_MaxBodyBytes(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	s := NewHttpServer(MaxBodyBytesOption(16))
+	handler := func(ctx *Context) {
+		var u user
+		if err := ctx.BindJSON(&u); err != nil {
+			return
+		}
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte(u.Name)
+	}
+	s.AddRoute(http.MethodPost, "/small", handler)
+	s.AddRoute(http.MethodPost, "/large", handler, MaxBodyBytes(64))
+	s.AddRoute(http.MethodPost, "/reject", func(ctx *Context) {
+		var u user
+		ctx.BindOrReject(&u)
+	})
+	s.AddRoute(http.MethodPost, "/reject-form", func(ctx *Context) {
+		var u struct {
+			Name string `form:"name"`
+		}
+		ctx.BindOrReject(&u)
+	})
+	s.AddRoute(http.MethodPost, "/form-value", func(ctx *Context) {
+		if _, err := ctx.FormValue("name").String(); err != nil {
+			return
+		}
+		ctx.RespStatusCode = http.StatusOK
+	})
+
+	testCases := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{
+			name:     "within limit",
+			path:     "/small",
+			body:     `{"name":"Tom"}`,
+			wantCode: http.StatusOK,
+			wantBody: "Tom",
+		},
+		{
+			name:     "too large",
+			path:     "/small",
+			body:     `{"name":"Tom and Jerry"}`,
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantBody: "REQUEST ENTITY TOO LARGE",
+		},
+		{
+			// 路由上的限制覆盖 server 的限制
+			name:     "route limit",
+			path:     "/large",
+			body:     `{"name":"Tom and Jerry"}`,
+			wantCode: http.StatusOK,
+			wantBody: "Tom and Jerry",
+		},
+		{
+			name:     "reject",
+			path:     "/reject",
+			body:     `{"name":"Tom and Jerry"}`,
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantBody: `{"type":"about:blank","title":"Request Entity Too Large","status":413,"detail":"请求体不能超过 16 字节"}`,
+		},
+		{
+			// 解析表单的时候超过限制
+			name:        "reject form",
+			path:        "/reject-form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=Tom+and+Jerry",
+			wantCode:    http.StatusRequestEntityTooLarge,
+			wantBody:    `{"type":"about:blank","title":"Request Entity Too Large","status":413,"detail":"请求体不能超过 16 字节"}`,
+		},
+		{
+			name:        "form value",
+			path:        "/form-value",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=Tom+and+Jerry",
+			wantCode:    http.StatusRequestEntityTooLarge,
+			wantBody:    "REQUEST ENTITY TOO LARGE",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			req.Header.Set("Content-Type", contentType)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestContext_BindJSONStrict(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	testCases := []struct {
+		name    string
+		strict  bool
+		body    string
+		wantErr string
+	}{
+		{
+			name: "unknown field",
+			body: `{"name":"Tom","age":18}`,
+		},
+		{
+			name:    "strict unknown field",
+			strict:  true,
+			body:    `{"name":"Tom","age":18}`,
+			wantErr: `json: unknown field "age"`,
+		},
+		{
+			name: "trailing space",
+			body: "{\"name\":\"Tom\"}\n ",
+		},
+		{
+			name:    "trailing garbage",
+			body:    `{"name":"Tom"}{"name":"Jerry"}`,
+			wantErr: "web: JSON 后面有多余的内容",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []ServerOption
+			if tc.strict {
+				opts = append(opts, StrictJSONOption())
+			}
+			var err error
+			s := NewHttpServer(opts...)
+			s.AddRoute(http.MethodPost, "/", func(ctx *Context) {
+				var u user
+				err = ctx.BindJSON(&u)
+			})
+			s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -3,10 +3,10 @@ package web
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"strconv"
+	"reflect"
 )
 
 type Context struct {
@@ -18,6 +18,22 @@ type Context struct {
 	RespStatusCode int
 	tplEngine      TemplateEngine
 	MatchedRoute   string
+	router         *router
+	writer         *responseWriter
+
+	// notFoundHandler NotFoundHandlerOption 设置的处理逻辑，StaticHandler 找不到文件的时候使用
+	notFoundHandler  HandleFunc
+	bindErrorHandler func(ctx *Context, err error)
+	strictJSON       bool
+
+	// rawBody 原始的请求体，调整大小限制的时候重新包装它
+	rawBody    io.ReadCloser
+	body       []byte
+	bodyCached bool
+
+	uploads map[string][]*UploadedFile
+	// tempFiles 上传文件时创建的临时文件，请求结束之后删除
+	tempFiles []string
 }
 
 func (c *Context) Render(tplName string, data any) error {
@@ -41,59 +57,56 @@ func (c *Context) RespJSON(status int, val any) error {
 	return nil
 }
 
+// BindJSON 把 JSON 请求体解码到 val 上，val 是结构体指针的时候会按照 validate tag 校验。
+// 请求体会被缓存，可以重复调用，见 Context.Body
 func (c *Context) BindJSON(val any) error {
 	if c.Req.Body == nil {
 		return errors.New("web: body is nil")
 	}
-	return json.NewDecoder(c.Req.Body).Decode(val)
+	body, err := c.Body()
+	if err != nil {
+		return err
+	}
+	if err = c.decodeJSON(body, val); err != nil {
+		return err
+	}
+	if rv := reflect.ValueOf(val); rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Struct {
+		return Validate(val)
+	}
+	return nil
 }
 
+// FormValue 读取表单参数，包括 URL 查询参数和 POST 的表单，同名参数取第一个值。
+// 请求体超过大小限制的时候返回的 error 包含 *http.MaxBytesError，并且把响应设置成 413
 func (c *Context) FormValue(key string) *StringValue {
-	if err := c.Req.ParseForm(); err != nil {
+	if err := c.parseForm(); err != nil {
 		return &StringValue{
-			err: err,
+			source: "form",
+			key:    key,
+			err:    &ValueError{Source: "form", Key: key, Err: err},
 		}
 	}
-	return &StringValue{
-		val: c.Req.FormValue(key),
-	}
+	return newStringValue("form", key, c.Req.Form[key])
 }
 
+// QueryValue 读取 URL 查询参数，同名参数取第一个值，所有的值可以通过 StringValue.Strings 拿到
 func (c *Context) QueryValue(key string) *StringValue {
+	return newStringValue("query", key, c.query()[key])
+}
+
+// query 解析 URL 查询参数，结果缓存起来避免重复解析
+func (c *Context) query() url.Values {
 	if c.queryValues == nil {
 		c.queryValues = c.Req.URL.Query()
 	}
-	val, ok := c.queryValues[key]
-	if !ok {
-		return &StringValue{
-			err: errors.New(fmt.Sprintf("query param:%s not exists.", key)),
-		}
-	}
-	return &StringValue{
-		val: val[1],
-	}
+	return c.queryValues
 }
 
+// PathValue 读取路径参数
 func (c *Context) PathValue(key string) *StringValue {
 	val, ok := c.PathParams[key]
 	if !ok {
-		return &StringValue{
-			err: errors.New(fmt.Sprintf("path param:%s not exixts.", key)),
-		}
-	}
-	return &StringValue{
-		val: val,
-	}
-}
-
-type StringValue struct {
-	val string
-	err error
-}
-
-func (s *StringValue) AsInt64() (int64, error) {
-	if s.err != nil {
-		return 0, s.err
+		return newStringValue("path", key, nil)
 	}
-	return strconv.ParseInt(s.val, 10, 64)
+	return newStringValue("path", key, []string{val})
 }
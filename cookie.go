@@ -0,0 +1,86 @@
+package web
+
+import (
+	"net/http"
+	"time"
+)
+
+// CookieOption 修改 SetCookie 生成的 cookie
+type CookieOption func(cookie *http.Cookie)
+
+// CookiePath 设置 cookie 的 Path，默认为 /
+func CookiePath(path string) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.Path = path
+	}
+}
+
+// CookieDomain 设置 cookie 的 Domain，默认只对当前域名有效
+func CookieDomain(domain string) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.Domain = domain
+	}
+}
+
+// CookieMaxAge 设置 cookie 的有效期，默认是会话 cookie，关闭浏览器就失效
+func CookieMaxAge(maxAge time.Duration) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.MaxAge = int(maxAge / time.Second)
+		cookie.Expires = time.Now().Add(maxAge)
+	}
+}
+
+// CookieSecure 设置 cookie 是否只能通过 HTTPS 发送，默认跟随当前请求是否为 HTTPS
+func CookieSecure(secure bool) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.Secure = secure
+	}
+}
+
+// CookieHttpOnly 设置 cookie 是否禁止 JavaScript 读取，默认禁止
+func CookieHttpOnly(httpOnly bool) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.HttpOnly = httpOnly
+	}
+}
+
+// CookieSameSite 设置 cookie 的 SameSite，默认为 Lax。
+// 设置为 None 的时候浏览器要求同时设置 Secure
+func CookieSameSite(sameSite http.SameSite) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.SameSite = sameSite
+	}
+}
+
+// SetCookie 设置 cookie。默认值偏向安全：Path 为 /，HttpOnly，SameSite=Lax，
+// HTTPS 请求默认带上 Secure。需要别的配置可以通过 opts 修改
+func (c *Context) SetCookie(name string, value string, opts ...CookieOption) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Req.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	for _, opt := range opts {
+		opt(cookie)
+	}
+	if v := cookie.String(); v != "" {
+		c.Header().Add("Set-Cookie", v)
+	}
+}
+
+// DeleteCookie 让浏览器删除 cookie。Path 和 Domain 需要和设置的时候保持一致
+func (c *Context) DeleteCookie(name string, opts ...CookieOption) {
+	opts = append(opts, func(cookie *http.Cookie) {
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+	})
+	c.SetCookie(name, "", opts...)
+}
+
+// Cookie 读取请求里面的 cookie，不存在的时候返回 http.ErrNoCookie
+func (c *Context) Cookie(name string) (*http.Cookie, error) {
+	return c.Req.Cookie(name)
+}
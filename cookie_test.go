@@ -0,0 +1,87 @@
+package web
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContext_SetCookie(t *testing.T) {
+	testCases := []struct {
+		name       string
+		tls        bool
+		setCookie  func(ctx *Context)
+		wantCookie string
+	}{
+		{
+			name: "default",
+			setCookie: func(ctx *Context) {
+				ctx.SetCookie("session", "abc")
+			},
+			wantCookie: "session=abc; Path=/; HttpOnly; SameSite=Lax",
+		},
+		{
+			name: "https",
+			tls:  true,
+			setCookie: func(ctx *Context) {
+				ctx.SetCookie("session", "abc")
+			},
+			wantCookie: "session=abc; Path=/; HttpOnly; Secure; SameSite=Lax",
+		},
+		{
+			name: "options",
+			setCookie: func(ctx *Context) {
+				ctx.SetCookie("theme", "dark", CookiePath("/app"), CookieDomain("example.com"),
+					CookieHttpOnly(false), CookieSecure(true), CookieSameSite(http.SameSiteStrictMode))
+			},
+			wantCookie: "theme=dark; Path=/app; Domain=example.com; Secure; SameSite=Strict",
+		},
+		{
+			name: "delete",
+			setCookie: func(ctx *Context) {
+				ctx.DeleteCookie("session")
+			},
+			wantCookie: "session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0; HttpOnly; SameSite=Lax",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHttpServer()
+			s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+				tc.setCookie(ctx)
+				ctx.RespStatusCode = http.StatusOK
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, []string{tc.wantCookie}, recorder.Header().Values("Set-Cookie"))
+		})
+	}
+}
+
+func TestContext_CookieMaxAge(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx := &Context{Req: httptest.NewRequest(http.MethodGet, "/", nil), Resp: recorder}
+	ctx.SetCookie("session", "abc", CookieMaxAge(time.Hour))
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, 3600, cookies[0].MaxAge)
+}
+
+func TestContext_Cookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	ctx := &Context{Req: req}
+	cookie, err := ctx.Cookie("session")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", cookie.Value)
+	_, err = ctx.Cookie("missing")
+	assert.ErrorIs(t, err, http.ErrNoCookie)
+}
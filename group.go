@@ -0,0 +1,70 @@
+package web
+
+import "fmt"
+
+// Group 路由分组。组内注册的路由共享前缀，并且会先经过组上的 middleware
+type Group struct {
+	prefix      string
+	server      *HttpServer
+	middlewares []Middleware
+}
+
+// Group 创建一个路由分组，prefix 必须以 / 开头，并且不能以 / 结尾
+func (hs *HttpServer) Group(prefix string, mids ...Middleware) *Group {
+	checkGroupPrefix(prefix)
+	return &Group{
+		prefix:      prefix,
+		server:      hs,
+		middlewares: mids,
+	}
+}
+
+// Group 在当前分组下创建子分组，子分组继承父分组的前缀和 middleware
+func (g *Group) Group(prefix string, mids ...Middleware) *Group {
+	checkGroupPrefix(prefix)
+	// 复制一份，避免兄弟分组之间共享底层数组
+	groupMids := make([]Middleware, 0, len(g.middlewares)+len(mids))
+	groupMids = append(groupMids, g.middlewares...)
+	groupMids = append(groupMids, mids...)
+	return &Group{
+		prefix:      g.prefix + prefix,
+		server:      g.server,
+		middlewares: groupMids,
+	}
+}
+
+// AddRoute 注册路由，path 是相对于分组前缀的路径。path 为 / 时注册的就是分组前缀本身。
+// 分组的 middleware 会排在 mids 之前执行
+func (g *Group) AddRoute(method string, path string, handler HandleFunc, mids ...Middleware) {
+	g.server.AddRoute(method, g.fullPath(path), handler, g.routeMids(mids)...)
+}
+
+// AddNamedRoute 注册具名路由，见 HttpServer.AddNamedRoute
+func (g *Group) AddNamedRoute(name string, method string, path string, handler HandleFunc, mids ...Middleware) {
+	g.server.AddNamedRoute(name, method, g.fullPath(path), handler, g.routeMids(mids)...)
+}
+
+func (g *Group) fullPath(path string) string {
+	if path == "" || path[0] != '/' {
+		panic("web: 路由必须以 / 开头")
+	}
+	if path == "/" {
+		return g.prefix
+	}
+	return g.prefix + path
+}
+
+func (g *Group) routeMids(mids []Middleware) []Middleware {
+	routeMids := make([]Middleware, 0, len(g.middlewares)+len(mids))
+	routeMids = append(routeMids, g.middlewares...)
+	return append(routeMids, mids...)
+}
+
+func checkGroupPrefix(prefix string) {
+	if prefix == "" || prefix[0] != '/' {
+		panic(fmt.Sprintf("web: 分组前缀必须以 / 开头 [%s]", prefix))
+	}
+	if prefix == "/" || prefix[len(prefix)-1] == '/' {
+		panic(fmt.Sprintf("web: 分组前缀不能以 / 结尾 [%s]", prefix))
+	}
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	var logs []string
+	mockMiddleware := func(name string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				logs = append(logs, name)
+				next(ctx)
+			}
+		}
+	}
+	mockHandler := func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte(ctx.MatchedRoute)
+	}
+
+	s := NewHttpServer()
+	api := s.Group("/api", mockMiddleware("api"))
+	api.AddRoute(http.MethodGet, "/", mockHandler)
+	v1 := api.Group("/v1", mockMiddleware("v1"))
+	v1.AddRoute(http.MethodGet, "/user/:id", mockHandler)
+	admin := api.Group("/admin", mockMiddleware("admin"))
+	admin.AddRoute(http.MethodGet, "/user", mockHandler)
+	s.AddRoute(http.MethodGet, "/user", mockHandler)
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+		wantLogs []string
+	}{
+		{
+			name:     "group root",
+			path:     "/api",
+			wantCode: http.StatusOK,
+			wantBody: "/api",
+			wantLogs: []string{"api"},
+		},
+		{
+			name:     "nested group",
+			path:     "/api/v1/user/123",
+			wantCode: http.StatusOK,
+			wantBody: "/api/v1/user/:id",
+			wantLogs: []string{"api", "v1"},
+		},
+		{
+			// 兄弟分组之间不能互相影响
+			name:     "sibling group",
+			path:     "/api/admin/user",
+			wantCode: http.StatusOK,
+			wantBody: "/api/admin/user",
+			wantLogs: []string{"api", "admin"},
+		},
+		{
+			name:     "no group",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "/user",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantLogs, logs)
+		})
+	}
+
+	assert.PanicsWithValue(t, "web: 分组前缀不能以 / 结尾 [/api/]", func() {
+		s.Group("/api/")
+	})
+	assert.PanicsWithValue(t, "web: 分组前缀必须以 / 开头 [api]", func() {
+		s.Group("api")
+	})
+	assert.PanicsWithValue(t, "web: 路由必须以 / 开头", func() {
+		api.AddRoute(http.MethodGet, "user", mockHandler)
+	})
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Hook 生命周期回调，例如在启动之前预热缓存，在退出之前刷新 TracerProvider
+type Hook func(ctx context.Context) error
+
+// OnStart 注册启动回调，按照注册顺序在开始接收请求之前执行
+func (hs *HttpServer) OnStart(hooks ...Hook) {
+	hs.onStart = append(hs.onStart, hooks...)
+}
+
+// OnShutdown 注册退出回调，按照注册顺序在处理完所有请求之后执行，例如：
+//
+//	server.OnShutdown(tracerProvider.Shutdown)
+func (hs *HttpServer) OnShutdown(hooks ...Hook) {
+	hs.onShutdown = append(hs.onShutdown, hooks...)
+}
+
+// Shutdown 优雅退出：不再接收新的连接和请求，等待处理中的请求结束之后执行 OnShutdown 回调。
+// ctx 的 deadline 同时约束等待请求和执行回调。
+// 如果在 deadline 之前没有等到请求结束，会强制关闭剩下的连接，并且依旧执行回调
+func (hs *HttpServer) Shutdown(ctx context.Context) error {
+	// 停止之后这些 listener 都关掉了，不能再用于热升级
+	hs.mutex.Lock()
+	hs.listeners = nil
+	hs.mutex.Unlock()
+	err := hs.server.Shutdown(ctx)
+	if err != nil {
+		_ = hs.server.Close()
+	}
+	// 一个回调失败不影响其它回调，尽可能地释放资源
+	for _, hook := range hs.onShutdown {
+		if hookErr := hook(ctx); hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	return err
+}
+
+// StartAndWait 监听 addr 并且提供服务，收到 signals 中的任一信号之后调用 Shutdown，
+// 并且最多等待 timeout。signals 为空时监听 SIGINT 和 SIGTERM
+func (hs *HttpServer) StartAndWait(addr string, timeout time.Duration, signals ...os.Signal) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	sigCtx, stop := signal.NotifyContext(context.Background(), signals...)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- hs.Serve(l)
+	}()
+	select {
+	case err = <-errCh:
+		// 还没有收到信号就退出了，说明启动失败或者别处调用了 Shutdown
+		return err
+	case <-sigCtx.Done():
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return hs.Shutdown(ctx)
+}
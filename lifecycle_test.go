@@ -0,0 +1,89 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHttpServer_Shutdown(t *testing.T) {
+	var logs []string
+	s := NewHttpServer()
+	s.OnStart(func(ctx context.Context) error {
+		logs = append(logs, "start")
+		return nil
+	})
+	s.OnShutdown(func(ctx context.Context) error {
+		logs = append(logs, "shutdown-1")
+		return errors.New("mock error")
+	}, func(ctx context.Context) error {
+		logs = append(logs, "shutdown-2")
+		return nil
+	})
+
+	handling := make(chan struct{})
+	s.AddRoute(http.MethodGet, "/slow", func(ctx *Context) {
+		close(handling)
+		time.Sleep(200 * time.Millisecond)
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("done")
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(l)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	respCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			respCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		respCh <- result{body: string(body), err: err}
+	}()
+
+	<-handling
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// 处理中的请求要等到结束，回调的 error 会返回，但是不影响后面的回调
+	assert.EqualError(t, s.Shutdown(ctx), "mock error")
+	assert.NoError(t, <-serveErr)
+
+	res := <-respCh
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+	assert.Equal(t, []string{"start", "shutdown-1", "shutdown-2"}, logs)
+
+	// 已经退出的服务器不再接收新的请求
+	_, err = http.Get("http://" + l.Addr().String() + "/slow")
+	assert.Error(t, err)
+}
+
+func TestHttpServer_OnStartError(t *testing.T) {
+	s := NewHttpServer()
+	s.OnStart(func(ctx context.Context) error {
+		return errors.New("mock error")
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.EqualError(t, s.Serve(l), "mock error")
+	// 启动失败之后监听的端口会被关闭
+	_, err = net.Dial("tcp", l.Addr().String())
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart systemd 传递过来的第一个文件描述符，前面三个是标准输入输出
+var listenFdsStart = 3
+
+// StartUnix 监听 unix domain socket 并且提供服务，见 ListenUnix
+func (hs *HttpServer) StartUnix(path string) error {
+	l, err := ListenUnix(path)
+	if err != nil {
+		return err
+	}
+	return hs.Serve(l)
+}
+
+// ListenUnix 监听 unix domain socket。
+// path 上遗留的 socket 文件会被删除，例如进程上次异常退出没有清理；其它类型的文件则返回 error
+func ListenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("web: %s 已经存在，并且不是 socket 文件", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// SystemdListeners 返回 systemd socket activation 传递过来的 listener，
+// 按照 LISTEN_FDS 的顺序排列。不是由 systemd 启动的时候返回 nil, nil。
+// 读取之后会清理 LISTEN_PID 等环境变量，避免子进程误用
+func SystemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("web: 非法的 LISTEN_FDS %q", os.Getenv("LISTEN_FDS"))
+	}
+	var names []string
+	if val := os.Getenv("LISTEN_FDNAMES"); val != "" {
+		names = strings.Split(val, ":")
+	}
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+	return filesListeners(listenFdsStart, n, names)
+}
+
+// filesListeners 把从 start 开始的 n 个继承来的文件描述符转换成 listener
+func filesListeners(start int, n int, names []string) ([]net.Listener, error) {
+	ls := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := start + i
+		name := "fd" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		// FileListener 会复制一份文件描述符，原来的可以直接关掉
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, opened := range ls {
+				_ = opened.Close()
+			}
+			return nil, fmt.Errorf("web: 文件描述符 %d(%s) 无法作为 listener: %w", fd, name, err)
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
@@ -0,0 +1,101 @@
+//go:build unix
+
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestHttpServer_ServeListeners(t *testing.T) {
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("hello")
+	})
+
+	public, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	sockPath := filepath.Join(t.TempDir(), "web.sock")
+	// 上次异常退出遗留下来的 socket 文件
+	stale, err := net.Listen("unix", sockPath)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+	unix, err := ListenUnix(sockPath)
+	require.NoError(t, err)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.ServeListeners(public, admin, unix)
+	}()
+
+	get := func(client *http.Client, url string) string {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	assert.Equal(t, "hello", get(http.DefaultClient, "http://"+public.Addr().String()))
+	assert.Equal(t, "hello", get(http.DefaultClient, "http://"+admin.Addr().String()))
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sockPath)
+		},
+	}}
+	assert.Equal(t, "hello", get(unixClient, "http://unix/"))
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, <-serveErr)
+}
+
+func TestListenUnix_notSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0600))
+	_, err := ListenUnix(path)
+	assert.EqualError(t, err, "web: "+path+" 已经存在，并且不是 socket 文件")
+}
+
+func TestSystemdListeners(t *testing.T) {
+	ls, err := SystemdListeners()
+	require.NoError(t, err)
+	assert.Nil(t, ls)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	require.NoError(t, err)
+	defer f.Close()
+	// 模拟 systemd 传递过来的文件描述符
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	listenFdsStart = fd
+	defer func() {
+		listenFdsStart = 3
+	}()
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "public")
+
+	ls, err = SystemdListeners()
+	require.NoError(t, err)
+	require.Len(t, ls, 1)
+	defer ls[0].Close()
+	assert.Equal(t, l.Addr().String(), ls[0].Addr().String())
+	_, ok := os.LookupEnv("LISTEN_PID")
+	assert.False(t, ok)
+}
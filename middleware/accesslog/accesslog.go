@@ -36,6 +36,8 @@ func NewMiddleware(options ...Option) web.Middleware {
 					Path:       ctx.Req.URL.Path,
 					HttpMethod: ctx.Req.Method,
 					Route:      ctx.MatchedRoute,
+					Status:     ctx.RespStatus(),
+					Bytes:      ctx.RespSize(),
 				}
 				val, _ := json.Marshal(l)
 				m.logFunc(string(val))
@@ -50,4 +52,6 @@ type accessLog struct {
 	Route      string `json:"route"`
 	HttpMethod string `json:"http_method"`
 	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	Bytes      int    `json:"bytes"`
 }
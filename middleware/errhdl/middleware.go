@@ -24,6 +24,10 @@ func (m *MiddlewareBuilder) Build() web.Middleware {
 	return func(next web.HandleFunc) web.HandleFunc {
 		return func(ctx *web.Context) {
 			next(ctx)
+			// 响应已经发出去了，替换不了
+			if ctx.Written() {
+				return
+			}
 			resp, ok := m.resp[ctx.RespStatusCode]
 			if ok {
 				ctx.RespData = resp
@@ -26,6 +26,16 @@ func WithSpanExporter(exporter sdktrace.SpanExporter) Option {
 	}
 }
 
+// WithTracerProvider 使用外部创建的 TracerProvider。
+// 退出的时候需要刷新还没有上报的 span，可以注册到服务器的退出回调上：
+//
+//	server.OnShutdown(tp.Shutdown)
+func WithTracerProvider(tp *sdktrace.TracerProvider) Option {
+	return func(m *Middleware) {
+		m.tracerProvider = tp
+	}
+}
+
 func NewMiddleware(options ...Option) web.Middleware {
 	m := &Middleware{}
 	for _, option := range options {
@@ -61,7 +71,7 @@ func NewMiddleware(options ...Option) web.Middleware {
 			}
 
 			// 怎么拿到响应的状态呢？比如说用户有没有返回错误，响应码是多少，怎么办？
-			span.SetAttributes(attribute.Int("http.status", ctx.RespStatusCode))
+			span.SetAttributes(attribute.Int("http.status", ctx.RespStatus()))
 		}
 	}
 }
@@ -20,11 +20,14 @@ func TestTracing(t *testing.T) {
 	//defer file.Close()
 
 	exporter := newJeager()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
 	middlewares := []web.Middleware{
-		NewMiddleware(WithSpanExporter(exporter)),
+		NewMiddleware(WithTracerProvider(tp)),
 	}
 
 	s := web.NewHttpServer(web.MiddlewaresOption(middlewares))
+	// 退出之前把还没有上报的 span 刷出去
+	s.OnShutdown(tp.Shutdown)
 
 	s.AddRoute(http.MethodGet, "/", func(ctx *web.Context) {
 		ctx.Resp.Write([]byte("hello, world"))
@@ -46,7 +49,9 @@ func TestTracing(t *testing.T) {
 		ctx.RespData = []byte("hello, world")
 	})
 
-	s.Start(":8081")
+	if err := s.StartAndWait(":8081", 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func newFile() (sdktrace.SpanExporter, *os.File) {
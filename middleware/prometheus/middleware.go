@@ -26,14 +26,16 @@ func (m *MiddlewareBuilder) Build() web.Middleware {
 			startTime := time.Now()
 			next(ctx)
 			endTime := time.Now()
-			go report(endTime.Sub(startTime), ctx, summaryVes)
+			go report(endTime.Sub(startTime), ctx.RespStatus(), ctx, summaryVes)
 		}
 	}
 }
 
-func report(dur time.Duration, ctx *web.Context, vec prometheus.ObserverVec) {
-	status := ctx.RespStatusCode
-	route := "unknown"
+func report(dur time.Duration, status int, ctx *web.Context, vec prometheus.ObserverVec) {
+	route := ctx.MatchedRoute
+	if route == "" {
+		route = "unknown"
+	}
 	ms := dur / time.Millisecond
 	vec.WithLabelValues(route, ctx.Req.Method, strconv.Itoa(status)).Observe(float64(ms))
 }
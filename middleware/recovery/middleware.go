@@ -13,8 +13,11 @@ func (m *MiddlewareBuilder) Build() web.Middleware {
 		return func(ctx *web.Context) {
 			defer func() {
 				if err := recover(); err != nil {
-					ctx.RespStatusCode = m.StatusCode
-					ctx.RespData = []byte(m.ErrMsg)
+					// 流式写入了一半的响应没办法再改成错误响应，只记录日志
+					if !ctx.Written() {
+						ctx.RespStatusCode = m.StatusCode
+						ctx.RespData = []byte(m.ErrMsg)
+					}
 					// 万一 LogFunc 也panic，那我们也无能为力了
 					m.LogFunc(ctx)
 				}
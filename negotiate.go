@@ -0,0 +1,199 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 内容协商支持的 MIME 类型
+const (
+	MIMEJSON     = "application/json"
+	MIMEXML      = "application/xml"
+	MIMEText     = "text/plain"
+	MIMEHTML     = "text/html"
+	MIMEProtobuf = "application/x-protobuf"
+)
+
+// ErrNotAcceptable 客户端的 Accept 里面没有可以返回的格式
+var ErrNotAcceptable = errors.New("web: 没有客户端可以接受的响应格式")
+
+// Negotiation 描述同一份数据可以用哪些格式返回
+type Negotiation struct {
+	// Offered 可以返回的 MIME 类型，按照服务端的偏好排列，客户端接受程度相同的时候取靠前的。
+	// 为空的时候默认是 JSON、XML，设置了 HTMLName 的时候加上 HTML，
+	// Data 实现了 proto.Message 的时候加上 protobuf，最后是纯文本
+	Offered []string
+	// Data 返回 JSON、XML、纯文本、protobuf 时使用的数据
+	Data any
+	// HTMLName 返回 HTML 时使用的模板名字，通过 TemplateEngineOption 设置的模板引擎渲染
+	HTMLName string
+	// HTMLData 渲染模板的数据，为空的时候使用 Data
+	HTMLData any
+}
+
+// Negotiate 根据请求的 Accept 头，从 offers 里面选出客户端最能接受的 MIME 类型。
+// 支持 q 值和 text/*、*/* 这样的通配，没有 Accept 头的时候返回 offers[0]，
+// 客户端都不接受的时候返回空字符串
+func (c *Context) Negotiate(offers ...string) string {
+	accepts := c.Req.Header.Values("Accept")
+	if len(accepts) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := parseAccept(strings.Join(accepts, ","))
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Respond 按照 Negotiate 选出来的格式返回 n，并且设置对应的 Content-Type。
+// 客户端都不接受的时候响应 406 并返回 ErrNotAcceptable，编码失败的时候响应 500
+func (c *Context) Respond(status int, n Negotiation) error {
+	offers := n.Offered
+	if len(offers) == 0 {
+		offers = []string{MIMEJSON, MIMEXML}
+		if n.HTMLName != "" {
+			offers = append(offers, MIMEHTML)
+		}
+		if _, ok := n.Data.(proto.Message); ok {
+			offers = append(offers, MIMEProtobuf)
+		}
+		offers = append(offers, MIMEText)
+	}
+	c.Header().Add("Vary", "Accept")
+	contentType := c.Negotiate(offers...)
+	if contentType == "" {
+		c.RespStatusCode = http.StatusNotAcceptable
+		c.RespData = []byte("NOT ACCEPTABLE")
+		return ErrNotAcceptable
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch contentType {
+	case MIMEJSON:
+		data, err = json.Marshal(n.Data)
+	case MIMEXML:
+		data, err = xml.Marshal(n.Data)
+	case MIMEText:
+		data = []byte(textOf(n.Data))
+	case MIMEHTML:
+		data, err = c.renderHTML(n)
+	case MIMEProtobuf:
+		msg, ok := n.Data.(proto.Message)
+		if !ok {
+			err = fmt.Errorf("web: %T 没有实现 proto.Message", n.Data)
+			break
+		}
+		data, err = proto.Marshal(msg)
+	default:
+		err = fmt.Errorf("web: 不支持的响应格式 %s", contentType)
+	}
+	if err != nil {
+		c.RespStatusCode = http.StatusInternalServerError
+		return err
+	}
+	if contentType == MIMEProtobuf {
+		c.Header().Set("Content-Type", contentType)
+	} else {
+		c.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	}
+	c.RespStatusCode = status
+	c.RespData = data
+	return nil
+}
+
+func (c *Context) renderHTML(n Negotiation) ([]byte, error) {
+	if c.tplEngine == nil {
+		return nil, errors.New("web: 没有设置模板引擎")
+	}
+	data := n.HTMLData
+	if data == nil {
+		data = n.Data
+	}
+	return c.tplEngine.Render(c.Req.Context(), n.HTMLName, data)
+}
+
+func textOf(data any) string {
+	switch v := data.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var res []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		res = append(res, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+	return res
+}
+
+// acceptQuality 返回 offer 的 q 值，以最具体的匹配为准：type/subtype > type/* > */*
+func acceptQuality(ranges []acceptRange, offer string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_Negotiate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		accept string
+		offers []string
+		want   string
+	}{
+		{
+			name:   "no accept",
+			offers: []string{MIMEJSON, MIMEXML},
+			want:   MIMEJSON,
+		},
+		{
+			name:   "exact",
+			accept: "application/xml",
+			offers: []string{MIMEJSON, MIMEXML},
+			want:   MIMEXML,
+		},
+		{
+			name:   "q value",
+			accept: "application/json;q=0.5, application/xml;q=0.8",
+			offers: []string{MIMEJSON, MIMEXML},
+			want:   MIMEXML,
+		},
+		{
+			// 客户端接受程度相同的时候，按照服务端的偏好
+			name:   "wildcard",
+			accept: "*/*",
+			offers: []string{MIMEXML, MIMEJSON},
+			want:   MIMEXML,
+		},
+		{
+			// 最具体的匹配决定 q 值
+			name:   "specific wins",
+			accept: "text/*;q=0.9, text/plain;q=0, */*;q=0.1",
+			offers: []string{MIMEText, MIMEHTML, MIMEJSON},
+			want:   MIMEHTML,
+		},
+		{
+			name:   "browser",
+			accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			offers: []string{MIMEJSON, MIMEXML, MIMEHTML},
+			want:   MIMEHTML,
+		},
+		{
+			name:   "not acceptable",
+			accept: "image/png",
+			offers: []string{MIMEJSON, MIMEXML},
+			want:   "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			ctx := &Context{Req: req}
+			assert.Equal(t, tc.want, ctx.Negotiate(tc.offers...))
+		})
+	}
+}
+
+type negotiateUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (u negotiateUser) String() string {
+	return "user " + u.Name
+}
+
+func TestContext_Respond(t *testing.T) {
+	tpl, err := template.New("user").Parse(`<p>{{ .Name }}</p>`)
+	require.NoError(t, err)
+	s := NewHttpServer(TemplateEngineOption(&GoTemplateEngine{T: tpl}))
+	s.AddRoute(http.MethodGet, "/user", func(ctx *Context) {
+		_ = ctx.Respond(http.StatusOK, Negotiation{
+			Data:     negotiateUser{Name: "Tom"},
+			HTMLName: "user",
+		})
+	})
+	s.AddRoute(http.MethodGet, "/proto", func(ctx *Context) {
+		_ = ctx.Respond(http.StatusOK, Negotiation{Data: wrapperspb.String("Tom")})
+	})
+
+	pb, err := proto.Marshal(wrapperspb.String("Tom"))
+	require.NoError(t, err)
+	testCases := []struct {
+		name            string
+		path            string
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "json",
+			path:            "/user",
+			accept:          "application/json",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"name":"Tom"}`,
+		},
+		{
+			name:            "xml",
+			path:            "/user",
+			accept:          "application/xml",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/xml; charset=utf-8",
+			wantBody:        `<negotiateUser><name>Tom</name></negotiateUser>`,
+		},
+		{
+			name:            "text",
+			path:            "/user",
+			accept:          "text/plain",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "user Tom",
+		},
+		{
+			name:            "html",
+			path:            "/user",
+			accept:          "text/html,application/xml;q=0.9,*/*;q=0.8",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<p>Tom</p>",
+		},
+		{
+			name:            "protobuf",
+			path:            "/proto",
+			accept:          "application/x-protobuf",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/x-protobuf",
+			wantBody:        string(pb),
+		},
+		{
+			// 数据不是 proto.Message 的时候不提供 protobuf
+			name:     "protobuf not offered",
+			path:     "/user",
+			accept:   "application/x-protobuf",
+			wantCode: http.StatusNotAcceptable,
+			wantBody: "NOT ACCEPTABLE",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept", tc.accept)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestContext_RespondWithoutTemplateEngine(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html")
+	ctx := &Context{Req: req, Resp: httptest.NewRecorder()}
+	err := ctx.Respond(http.StatusOK, Negotiation{HTMLName: "user"})
+	assert.EqualError(t, err, "web: 没有设置模板引擎")
+	assert.Equal(t, http.StatusInternalServerError, ctx.RespStatusCode)
+}
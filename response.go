@@ -0,0 +1,151 @@
+package web
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// responseWriter 包装 http.ResponseWriter，记录实际发送的状态码和字节数。
+// 不管是通过 Context.Write 还是直接操作 Context.Resp，写出的数据都会被记录下来
+type responseWriter struct {
+	http.ResponseWriter
+	ctx *Context
+
+	wroteHeader bool
+	hijacked    bool
+	// status 实际发送的状态码，发送之后 handler 依旧可以修改 RespStatusCode
+	status int
+	size   int
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader || w.hijacked {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	w.ctx.RespStatusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// Flush 实现 http.Flusher，底层不支持的时候什么也不做
+func (w *responseWriter) Flush() {
+	_ = w.flush()
+}
+
+func (w *responseWriter) flush() error {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return errors.New("web: 当前连接不支持 Flush")
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	flusher.Flush()
+	return nil
+}
+
+// Hijack 实现 http.Hijacker，接管连接之后框架不会再写响应
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("web: 当前连接不支持 Hijack")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap 返回原始的 http.ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Header 返回响应头。和 RespStatusCode 一样，响应头在发送响应的时候才会真正写出，
+// 所以 middleware 在 handler 之后依旧可以修改。开始写入之后再修改就没有效果了
+func (c *Context) Header() http.Header {
+	return c.Resp.Header()
+}
+
+// Write 直接把 data 写给客户端，用于大文件下载、长时间输出等不适合缓存在 RespData 里面的场景。
+// 第一次写入的时候会先发送响应头，状态码取 RespStatusCode，没有设置的话为 200。
+// 开始写入之后 RespStatusCode 和 RespData 都不再生效
+func (c *Context) Write(data []byte) (int, error) {
+	c.writeHeader()
+	return c.Resp.Write(data)
+}
+
+// Flush 把已经写入的数据立刻发送给客户端，还没有发送响应头的话会先发送响应头
+func (c *Context) Flush() error {
+	c.writeHeader()
+	if w, ok := c.Resp.(*responseWriter); ok {
+		return w.flush()
+	}
+	flusher, ok := c.Resp.(http.Flusher)
+	if !ok {
+		return errors.New("web: 当前连接不支持 Flush")
+	}
+	flusher.Flush()
+	return nil
+}
+
+// Written 响应头是否已经发送，或者连接已经被接管。
+// 为 true 的时候 middleware 再修改 RespStatusCode 和 RespData 也不会有效果了
+func (c *Context) Written() bool {
+	w := c.respWriter()
+	return w.wroteHeader || w.hijacked
+}
+
+// RespStatus 实际发送给客户端的状态码。
+// 没有设置 RespStatusCode 的时候最终会响应 200，middleware 在 next 之后拿到的是 0，统计状态码的时候应该用这个方法
+func (c *Context) RespStatus() int {
+	w := c.respWriter()
+	if w.wroteHeader {
+		return w.status
+	}
+	if c.RespStatusCode == 0 && !w.hijacked {
+		return http.StatusOK
+	}
+	return c.RespStatusCode
+}
+
+// RespSize 响应体的字节数。已经开始写入的时候是实际写出的字节数，否则是 RespData 的长度
+func (c *Context) RespSize() int {
+	if w := c.respWriter(); w.wroteHeader {
+		return w.size
+	}
+	return len(c.RespData)
+}
+
+func (c *Context) writeHeader() {
+	if c.Written() {
+		return
+	}
+	if c.RespStatusCode == 0 {
+		c.RespStatusCode = http.StatusOK
+	}
+	c.Resp.WriteHeader(c.RespStatusCode)
+}
+
+// respWriter 返回记录响应状态的 responseWriter，
+// 没有经过 ServeHTTP 创建的 Context 会在这里补上
+func (c *Context) respWriter() *responseWriter {
+	if c.writer == nil {
+		c.writer = &responseWriter{ResponseWriter: c.Resp, ctx: c}
+		c.Resp = c.writer
+	}
+	return c.writer
+}
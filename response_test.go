@@ -0,0 +1,141 @@
+package web
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContext_Write(t *testing.T) {
+	type result struct {
+		status     int
+		respStatus int
+		size       int
+		written    bool
+	}
+	var res result
+	s := NewHttpServer(MiddlewaresOption([]Middleware{
+		func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				next(ctx)
+				res = result{status: ctx.RespStatusCode, respStatus: ctx.RespStatus(), size: ctx.RespSize(), written: ctx.Written()}
+			}
+		},
+	}))
+	s.AddRoute(http.MethodGet, "/stream", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusAccepted
+		_, _ = ctx.Write([]byte("hello "))
+		require.NoError(t, ctx.Flush())
+		_, _ = ctx.Write([]byte("world"))
+		// 开始写入之后不再生效
+		ctx.RespStatusCode = http.StatusInternalServerError
+		ctx.RespData = []byte("ignored")
+	})
+	s.AddRoute(http.MethodGet, "/raw", func(ctx *Context) {
+		ctx.Resp.WriteHeader(http.StatusCreated)
+		_, _ = ctx.Resp.Write([]byte("raw"))
+	})
+	s.AddRoute(http.MethodGet, "/buffered", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("buffered")
+	})
+	s.AddRoute(http.MethodGet, "/default", func(ctx *Context) {
+		ctx.RespData = []byte("default")
+	})
+
+	testCases := []struct {
+		name        string
+		path        string
+		wantCode    int
+		wantBody    string
+		wantFlushed bool
+		wantResult  result
+	}{
+		{
+			name:        "stream",
+			path:        "/stream",
+			wantCode:    http.StatusAccepted,
+			wantBody:    "hello world",
+			wantFlushed: true,
+			wantResult:  result{status: http.StatusInternalServerError, respStatus: http.StatusAccepted, size: 11, written: true},
+		},
+		{
+			// 直接操作 Resp 也能拿到状态码和字节数
+			name:       "raw",
+			path:       "/raw",
+			wantCode:   http.StatusCreated,
+			wantBody:   "raw",
+			wantResult: result{status: http.StatusCreated, respStatus: http.StatusCreated, size: 3, written: true},
+		},
+		{
+			name:       "buffered",
+			path:       "/buffered",
+			wantCode:   http.StatusOK,
+			wantBody:   "buffered",
+			wantResult: result{status: http.StatusOK, respStatus: http.StatusOK, size: 8},
+		},
+		{
+			// 没有设置状态码，middleware 拿到的 RespStatusCode 是 0，但是客户端收到的是 200
+			name:       "default status",
+			path:       "/default",
+			wantCode:   http.StatusOK,
+			wantBody:   "default",
+			wantResult: result{respStatus: http.StatusOK, size: 7},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantFlushed, recorder.Flushed)
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
+
+func TestContext_WriteStreaming(t *testing.T) {
+	next := make(chan struct{})
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		_, _ = ctx.Write([]byte("first\n"))
+		_ = ctx.Flush()
+		<-next
+		_, _ = ctx.Write([]byte("second\n"))
+	})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	// handler 还没有结束，客户端已经能够读到第一段数据
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", line)
+	close(next)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", line)
+}
+
+func TestHttpServer_HandlerTimeoutStreaming(t *testing.T) {
+	s := NewHttpServer(HandlerTimeoutOption(20 * time.Millisecond))
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		_, _ = ctx.Write([]byte("partial"))
+		<-ctx.Req.Context().Done()
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	// 已经发出去的响应不会被改成 503
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "partial", recorder.Body.String())
+}
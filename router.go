@@ -2,12 +2,15 @@ package web
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 type router struct {
 	trees map[string]*routeNode
+	names map[string]*namedRoute // 路由名字到路由的映射，用于反向生成 URL
 }
 
 type HandleFunc func(ctx *Context)
@@ -15,10 +18,30 @@ type HandleFunc func(ctx *Context)
 func newRouter() *router {
 	return &router{
 		trees: map[string]*routeNode{},
+		names: map[string]*namedRoute{},
 	}
 }
 
-func (r *router) addRoute(method string, path string, handler HandleFunc) {
+// addRoute 注册路由，mdls 是路由级别的 middleware，只作用于该路由的 handler
+func (r *router) addRoute(method string, path string, handler HandleFunc, mdls ...Middleware) {
+	root := r.findOrCreateNode(method, path)
+	if root.handler != nil {
+		panic(fmt.Sprintf("web: 路由冲突[%s]", path))
+	}
+	root.handler = handler
+	root.route = path
+	root.mdls = mdls
+}
+
+// use 在路径模式上注册 middleware。
+// 以 * 结尾的模式，例如 /admin/*，会作用于其下所有层级的路径；其余模式只作用于完全匹配的路径
+func (r *router) use(method string, path string, mdls ...Middleware) {
+	root := r.findOrCreateNode(method, path)
+	root.patternMdls = append(root.patternMdls, mdls...)
+}
+
+// findOrCreateNode 校验 path，并且找到 path 对应的节点，中间缺失的节点都会被创建
+func (r *router) findOrCreateNode(method string, path string) *routeNode {
 	if path == "" {
 		panic("web: 路由是空字符串")
 	}
@@ -38,11 +61,7 @@ func (r *router) addRoute(method string, path string, handler HandleFunc) {
 		r.trees[method] = root
 	}
 	if path == "/" {
-		if root.handler != nil {
-			panic("web: 路由冲突[/]")
-		}
-		root.handler = handler
-		return
+		return root
 	}
 
 	segs := strings.Split(path[1:], "/")
@@ -53,103 +72,178 @@ func (r *router) addRoute(method string, path string, handler HandleFunc) {
 		}
 		root = root.findChildOrCreate(seg)
 	}
-	if root.handler != nil {
-		panic(fmt.Sprintf("web: 路由冲突[%s]", path))
-	}
-	root.handler = handler
-	root.route = path
+	return root
 }
 
+// findRoute 查找 path 对应的路由，只有注册了 handler 的节点才算命中。
+// 匹配失败时会回溯尝试其它候选，候选的优先级见 routeNode.match
 func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
 	root, ok := r.trees[method]
 	if !ok {
 		return nil, false
 	}
 	if path == "/" {
-		return &matchInfo{node: root}, true
+		if root.handler == nil {
+			return nil, false
+		}
+		return &matchInfo{node: root, mdls: root.patternMdls}, true
 	}
 
 	segs := strings.Split(strings.Trim(path, "/"), "/")
 	mi := &matchInfo{}
-	for _, s := range segs {
-		var matchParam bool
-		root, matchParam, ok = root.findChild(s)
-		if !ok {
-			return nil, false
+	mi.node = root.match(segs, mi)
+	if mi.node == nil {
+		return nil, false
+	}
+	mi.mdls = r.findMdls(root, segs, false)
+	return mi, true
+}
+
+// findAllowedMethods 返回所有能够处理 path 的 HTTP 方法，按字典序排列。
+// 能处理 GET 的 path 也能处理 HEAD，见 HttpServer.serve
+func (r *router) findAllowedMethods(path string) []string {
+	var methods []string
+	hasGet, hasHead := false, false
+	for method := range r.trees {
+		if _, ok := r.findRoute(method, path); ok {
+			methods = append(methods, method)
+			hasGet = hasGet || method == http.MethodGet
+			hasHead = hasHead || method == http.MethodHead
 		}
-		if matchParam {
-			// 先检查是否匹配正则
-			if root.regPattern != "" {
-				reg, _ := regexp.Compile(root.regPattern)
-				if reg.MatchString(s) {
-					mi.addValue(root.regKey, s)
-				} else {
-					mi.addValue(root.path[1:], s)
+	}
+	if hasGet && !hasHead {
+		methods = append(methods, http.MethodHead)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// findMdls 按层遍历路由树，收集所有能够匹配 segs 的路径模式 middleware。
+// 结果按照层级排序，越靠近根节点越先执行；同一层级内按照通配符、正则、参数、静态的顺序。
+// segs 是路由模式本身而不是请求路径时，pattern 为 true，此时 *、*name 段只有通配符能够覆盖
+func (r *router) findMdls(root *routeNode, segs []string, pattern bool) []Middleware {
+	var res []Middleware
+	queue := []*routeNode{root}
+	for _, seg := range segs {
+		var next []*routeNode
+		for _, node := range queue {
+			if node.starChild != nil {
+				// 通配符上的 middleware 作用于其下所有层级，所以命中就收集
+				res = append(res, node.starChild.patternMdls...)
+				next = append(next, node.starChild)
+			}
+			for _, child := range node.regChildren {
+				// 路由模式里面的正则段是 :id(\d+) 本身，只能和同一个正则段对上
+				if pattern && child.path == seg || !pattern && child.regExpr.MatchString(seg) {
+					next = append(next, child)
 				}
-			} else {
-				mi.addValue(root.path[1:], s)
+			}
+			if node.paramChild != nil && !(pattern && seg[0] == '*') {
+				next = append(next, node.paramChild)
+			}
+			if child, ok := node.children[seg]; ok {
+				next = append(next, child)
 			}
 		}
+		queue = next
 	}
-	mi.node = root
-	return mi, true
+	// 剩下的节点和 path 完全匹配，通配符节点在上面已经收集过了
+	for _, node := range queue {
+		if node.path[0] != '*' {
+			res = append(res, node.patternMdls...)
+		}
+	}
+	return res
 }
 
 type routeNode struct {
-	path       string
-	children   map[string]*routeNode
-	handler    HandleFunc
-	starChild  *routeNode
-	paramChild *routeNode
-	isEndStar  bool
-	regPattern string
-	regKey     string
-	route      string // 完整的路由
-}
-
-func (rn *routeNode) findChild(seg string) (*routeNode, bool, bool) {
-	// 如果通配符在最后一段，那么匹配后面多段路由。例如 /a/b/* 可以匹配 /a/b/c/d/e/f
-	if rn.isEndStar {
-		return rn, false, true
-	}
-	if rn.starChild != nil &&
-		rn.starChild.children == nil &&
-		rn.starChild.starChild == nil &&
-		rn.starChild.paramChild == nil {
-		rn.starChild.isEndStar = true
-		return rn.starChild, false, true
-	}
+	path        string
+	children    map[string]*routeNode
+	handler     HandleFunc
+	starChild   *routeNode
+	paramChild  *routeNode   // 普通参数路由，例如 :id
+	regChildren []*routeNode // 正则路由，按照注册顺序排列，例如 :id(\d+)
+	regPattern  string
+	regKey      string
+	regExpr     *regexp.Regexp // 注册时编译好的正则，要求整段匹配
+	route       string         // 完整的路由
+	name        string         // 路由的名字，可以为空
+	mdls        []Middleware   // 路由级别的 middleware，只作用于该节点的 handler
+	patternMdls []Middleware   // 通过 use 注册在路径模式上的 middleware
+}
 
-	if rn.children == nil {
-		if rn.paramChild != nil {
-			return rn.paramChild, true, true
+// match 在 rn 的子树中匹配 segs，返回命中的节点，没有命中返回 nil。
+// 每一段按照 静态路由 > 正则路由（按注册顺序）> 参数路由 > 通配符路由 的优先级依次尝试，
+// 某个候选的子树匹配失败时回溯到下一个候选。
+// 通配符匹配一段；如果通配符节点注册了 handler，并且其子树匹配失败，那么它匹配剩下的所有段，
+// 例如 /a/b/* 可以匹配 /a/b/c/d/e/f。
+// 具名通配符 /files/*filepath 匹配 /files/css/a.css 时，参数 filepath 的值为 css/a.css；
+// 位于中间的具名通配符 /users/*name/profile 只匹配一段
+func (rn *routeNode) match(segs []string, mi *matchInfo) *routeNode {
+	if len(segs) == 0 {
+		if rn.handler == nil {
+			return nil
 		}
-		return rn.starChild, false, rn.starChild != nil
+		return rn
 	}
-	res, ok := rn.children[seg]
-	if !ok {
-		if rn.paramChild != nil {
-			return rn.paramChild, true, true
+	seg, rest := segs[0], segs[1:]
+	if child, ok := rn.children[seg]; ok {
+		if res := child.match(rest, mi); res != nil {
+			return res
+		}
+	}
+	for _, child := range rn.regChildren {
+		if !child.regExpr.MatchString(seg) {
+			continue
+		}
+		if res := child.match(rest, mi); res != nil {
+			mi.addValue(child.regKey, seg)
+			return res
+		}
+	}
+	if rn.paramChild != nil {
+		if res := rn.paramChild.match(rest, mi); res != nil {
+			mi.addValue(rn.paramChild.path[1:], seg)
+			return res
 		}
-		return rn.starChild, false, rn.starChild != nil
 	}
-	return res, false, ok
+	if rn.starChild != nil {
+		// 具名通配符会把匹配到的内容放到参数里面，匿名的 * 不记录
+		name := rn.starChild.path[1:]
+		if res := rn.starChild.match(rest, mi); res != nil {
+			if name != "" {
+				mi.addValue(name, seg)
+			}
+			return res
+		}
+		if rn.starChild.handler != nil {
+			if name != "" {
+				mi.addValue(name, strings.Join(segs, "/"))
+			}
+			return rn.starChild
+		}
+	}
+	return nil
 }
 
 // findChildOrCreate 查找子节点，如果子节点不存在就创建一个
 // 并且将子节点放回去了 children 中
 func (rn *routeNode) findChildOrCreate(seg string) *routeNode {
-	if seg == "*" {
+	// 以 * 开头的是通配符路由，* 后面可以跟上名字，例如 *filepath
+	if seg[0] == '*' {
 		if rn.starChild == nil {
 			rn.starChild = &routeNode{path: seg}
+		} else if rn.starChild.path != seg {
+			panic(fmt.Sprintf("web: 路由冲突，通配符路由冲突，已有 %s，新注册 %s", rn.starChild.path, seg))
 		}
 		return rn.starChild
 	}
 
 	// 以 : 开头，我们认为是参数路由/正则路由
 	if seg[0] == ':' {
-		if rn.starChild != nil {
-			panic(fmt.Sprintf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和参数路由 [%s]", seg))
+		key, pattern := getRegParam(seg)
+		if pattern != "" {
+			return rn.findRegChildOrCreate(seg, key, pattern)
 		}
 		if rn.paramChild != nil {
 			if rn.paramChild.path != seg {
@@ -157,11 +251,6 @@ func (rn *routeNode) findChildOrCreate(seg string) *routeNode {
 			}
 		} else {
 			rn.paramChild = &routeNode{path: seg}
-			key, pattern := getRegParam(seg)
-			if pattern != "" {
-				rn.paramChild.regKey = key
-				rn.paramChild.regPattern = pattern
-			}
 		}
 		return rn.paramChild
 	}
@@ -177,6 +266,33 @@ func (rn *routeNode) findChildOrCreate(seg string) *routeNode {
 	return child
 }
 
+// findRegChildOrCreate 查找正则子节点，不存在就创建一个，并且追加到 regChildren 末尾
+func (rn *routeNode) findRegChildOrCreate(seg string, key string, pattern string) *routeNode {
+	for _, child := range rn.regChildren {
+		if child.path == seg {
+			return child
+		}
+	}
+	child := &routeNode{
+		path:       seg,
+		regKey:     key,
+		regPattern: pattern,
+		regExpr:    compileRegParam(seg, pattern),
+	}
+	rn.regChildren = append(rn.regChildren, child)
+	return child
+}
+
+// compileRegParam 编译正则路由的正则，非法的正则直接 panic。
+// 正则要求匹配整段路径，所以统一加上首尾锚点
+func compileRegParam(seg string, pattern string) *regexp.Regexp {
+	expr, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("web: 非法正则路由 [%s]: %v", seg, err))
+	}
+	return expr
+}
+
 func getRegParam(seg string) (key string, pattern string) {
 	leftBracketIndex := strings.Index(seg, "(")
 	if leftBracketIndex > 0 && seg[len(seg)-1] == ')' {
@@ -189,6 +305,7 @@ func getRegParam(seg string) (key string, pattern string) {
 type matchInfo struct {
 	node       *routeNode
 	pathParams map[string]string
+	mdls       []Middleware // 命中的路径模式 middleware
 }
 
 func (m *matchInfo) addValue(key string, value string) {
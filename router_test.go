@@ -177,15 +177,17 @@ func Test_router_addRoute(t *testing.T) {
 					},
 					"reg": {
 						path: "reg",
-						paramChild: &routeNode{
-							path:       ":id(\\d+)",
-							handler:    mockHandler,
-							regKey:     "id",
-							regPattern: "\\d+",
-							children: map[string]*routeNode{
-								"bc": {
-									path:    "bc",
-									handler: mockHandler,
+						regChildren: []*routeNode{
+							{
+								path:       ":id(\\d+)",
+								handler:    mockHandler,
+								regKey:     "id",
+								regPattern: "\\d+",
+								children: map[string]*routeNode{
+									"bc": {
+										path:    "bc",
+										handler: mockHandler,
+									},
 								},
 							},
 						},
@@ -233,6 +235,11 @@ func Test_router_addRoute(t *testing.T) {
 	assert.PanicsWithValue(t, "web: 非法路由。不允许使用 //a/b, /a//b 之类的路由, [//a/b]", func() {
 		r.addRoute(http.MethodGet, "//a/b", mockHandler)
 	})
+
+	// 非法正则
+	assert.PanicsWithValue(t, "web: 非法正则路由 [:id([0-9)]: error parsing regexp: missing closing ]: `[0-9)$`", func() {
+		r.addRoute(http.MethodGet, "/reg/:id([0-9)", mockHandler)
+	})
 }
 
 func (r *router) equal(y router) (string, bool) {
@@ -263,6 +270,37 @@ func (rn *routeNode) equal(y *routeNode) (string, bool) {
 		return fmt.Sprintf("%s 节点 handler 不相等 x %s, y %s", rn.path, nhv.Type().String(), yhv.Type().String()), false
 	}
 
+	if rn.regPattern != y.regPattern || rn.regKey != y.regKey {
+		return fmt.Sprintf("%s 节点正则不相等 x %s, y %s", rn.path, rn.regPattern, y.regPattern), false
+	}
+
+	if (rn.starChild == nil) != (y.starChild == nil) {
+		return fmt.Sprintf("%s 通配符子节点不相等", rn.path), false
+	}
+	if rn.starChild != nil {
+		if str, ok := rn.starChild.equal(y.starChild); !ok {
+			return rn.path + "-" + str, ok
+		}
+	}
+
+	if (rn.paramChild == nil) != (y.paramChild == nil) {
+		return fmt.Sprintf("%s 参数子节点不相等", rn.path), false
+	}
+	if rn.paramChild != nil {
+		if str, ok := rn.paramChild.equal(y.paramChild); !ok {
+			return rn.path + "-" + str, ok
+		}
+	}
+
+	if len(rn.regChildren) != len(y.regChildren) {
+		return fmt.Sprintf("%s 正则子节点长度不等", rn.path), false
+	}
+	for i, c := range rn.regChildren {
+		if str, ok := c.equal(y.regChildren[i]); !ok {
+			return rn.path + "-" + str, ok
+		}
+	}
+
 	if len(rn.children) != len(y.children) {
 		return fmt.Sprintf("%s 子节点长度不等", rn.path), false
 	}
@@ -338,6 +376,10 @@ func Test_router_findRoute(t *testing.T) {
 			method: http.MethodPost,
 			path:   "/reg/:id(\\d+)/bc",
 		},
+		{
+			method: http.MethodPost,
+			path:   "/reg/*",
+		},
 	}
 
 	mockHandler := func(ctx *Context) {}
@@ -384,15 +426,10 @@ func Test_router_findRoute(t *testing.T) {
 			},
 		},
 		{
+			// 节点存在但是没有 handler，不算命中
 			name:   "no handler",
 			method: http.MethodPost,
 			path:   "/order",
-			found:  true,
-			mi: &matchInfo{
-				node: &routeNode{
-					path: "order",
-				},
-			},
 		},
 		{
 			name:   "two layer",
@@ -528,6 +565,32 @@ func Test_router_findRoute(t *testing.T) {
 				pathParams: map[string]string{"id": "123"},
 			},
 		},
+		{
+			// 正则不匹配时落到通配符路由，并且不会绑定参数
+			name:   "/reg/id() mismatch",
+			method: http.MethodPost,
+			path:   "/reg/abc",
+			found:  true,
+			mi: &matchInfo{
+				node: &routeNode{
+					path:    "*",
+					handler: mockHandler,
+				},
+			},
+		},
+		{
+			// 正则要求整段匹配，12a 只有部分匹配
+			name:   "/reg/id() partial",
+			method: http.MethodPost,
+			path:   "/reg/12a/bc",
+			found:  true,
+			mi: &matchInfo{
+				node: &routeNode{
+					path:    "*",
+					handler: mockHandler,
+				},
+			},
+		},
 	}
 
 	r := newRouter()
@@ -550,3 +613,286 @@ func Test_router_findRoute(t *testing.T) {
 		})
 	}
 }
+
+func Test_router_findMdls(t *testing.T) {
+	var logs []string
+	mockMiddleware := func(name string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				logs = append(logs, name)
+				next(ctx)
+			}
+		}
+	}
+	mockHandler := func(ctx *Context) {}
+
+	r := newRouter()
+	r.use(http.MethodGet, "/", mockMiddleware("/"))
+	r.use(http.MethodGet, "/*", mockMiddleware("/*"))
+	r.use(http.MethodGet, "/admin", mockMiddleware("/admin"))
+	r.use(http.MethodGet, "/admin/*", mockMiddleware("/admin/*"))
+	r.use(http.MethodGet, "/admin/:id", mockMiddleware("/admin/:id"))
+	r.use(http.MethodGet, "/admin/user", mockMiddleware("/admin/user"))
+	r.use(http.MethodGet, "/reg/:id(^\\d+$)", mockMiddleware("/reg/:id"))
+	r.addRoute(http.MethodGet, "/", mockHandler)
+	r.addRoute(http.MethodGet, "/admin", mockHandler)
+	r.addRoute(http.MethodGet, "/admin/:id", mockHandler, mockMiddleware("route"))
+	r.addRoute(http.MethodGet, "/admin/user", mockHandler)
+	r.addRoute(http.MethodGet, "/admin/user/detail", mockHandler)
+	r.addRoute(http.MethodGet, "/reg/:id(^\\d+$)", mockHandler)
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantLogs []string
+	}{
+		{
+			name:     "root",
+			path:     "/",
+			wantLogs: []string{"/"},
+		},
+		{
+			name:     "admin",
+			path:     "/admin",
+			wantLogs: []string{"/*", "/admin"},
+		},
+		{
+			name:     "admin param",
+			path:     "/admin/123",
+			wantLogs: []string{"/*", "/admin/*", "/admin/:id", "route"},
+		},
+		{
+			name:     "admin static",
+			path:     "/admin/user",
+			wantLogs: []string{"/*", "/admin/*", "/admin/:id", "/admin/user"},
+		},
+		{
+			name:     "admin deep",
+			path:     "/admin/user/detail",
+			wantLogs: []string{"/*", "/admin/*"},
+		},
+		{
+			name:     "reg",
+			path:     "/reg/123",
+			wantLogs: []string{"/*", "/reg/:id"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			mi, found := r.findRoute(http.MethodGet, tc.path)
+			assert.True(t, found)
+			handler := mi.node.handler
+			for i := len(mi.node.mdls) - 1; i >= 0; i-- {
+				handler = mi.node.mdls[i](handler)
+			}
+			for i := len(mi.mdls) - 1; i >= 0; i-- {
+				handler = mi.mdls[i](handler)
+			}
+			handler(&Context{})
+			assert.Equal(t, tc.wantLogs, logs)
+		})
+	}
+
+	// 只注册了 middleware 的通配符不影响参数路由的注册和查找
+	r.use(http.MethodPost, "/order/*", mockMiddleware("/order/*"))
+	r.addRoute(http.MethodPost, "/order/:id", mockHandler)
+	logs = nil
+	mi, found := r.findRoute(http.MethodPost, "/order/123")
+	assert.True(t, found)
+	assert.Equal(t, map[string]string{"id": "123"}, mi.pathParams)
+	assert.Equal(t, 1, len(mi.mdls))
+}
+
+func Test_router_findRoute_backtracking(t *testing.T) {
+	testRoutes := []string{
+		"/a/b/d",
+		"/a/:x/c",
+		"/u/:id(^\\d+$)",
+		"/u/:name",
+		"/u/:code([a-z]{3})",
+		"/p/:id(\\d+)/x",
+		"/p/:name/y",
+		"/s/:id",
+		"/s/*",
+		"/w/*",
+		"/w/*/abc",
+	}
+
+	r := newRouter()
+	for _, path := range testRoutes {
+		// 用路由本身作为响应，方便区分命中的节点
+		route := path
+		r.addRoute(http.MethodGet, route, func(ctx *Context) {
+			ctx.RespData = []byte(route)
+		})
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		found      bool
+		wantRoute  string
+		wantParams map[string]string
+	}{
+		{
+			// /a/b 是死路，回溯到 /a/:x/c
+			name:       "dead-end static child",
+			path:       "/a/b/c",
+			found:      true,
+			wantRoute:  "/a/:x/c",
+			wantParams: map[string]string{"x": "b"},
+		},
+		{
+			name:      "static first",
+			path:      "/a/b/d",
+			found:     true,
+			wantRoute: "/a/b/d",
+		},
+		{
+			name:       "regex before param",
+			path:       "/u/123",
+			found:      true,
+			wantRoute:  "/u/:id(^\\d+$)",
+			wantParams: map[string]string{"id": "123"},
+		},
+		{
+			name:       "regex in registration order",
+			path:       "/u/abc",
+			found:      true,
+			wantRoute:  "/u/:code([a-z]{3})",
+			wantParams: map[string]string{"code": "abc"},
+		},
+		{
+			name:       "param after regex mismatch",
+			path:       "/u/tom_1",
+			found:      true,
+			wantRoute:  "/u/:name",
+			wantParams: map[string]string{"name": "tom_1"},
+		},
+		{
+			// 正则命中了，但是子树匹配失败，回溯到参数路由，并且不会留下正则的参数
+			name:       "backtrack from regex subtree",
+			path:       "/p/12/y",
+			found:      true,
+			wantRoute:  "/p/:name/y",
+			wantParams: map[string]string{"name": "12"},
+		},
+		{
+			name:       "regex subtree",
+			path:       "/p/12/x",
+			found:      true,
+			wantRoute:  "/p/:id(\\d+)/x",
+			wantParams: map[string]string{"id": "12"},
+		},
+		{
+			name:       "param before star",
+			path:       "/s/1",
+			found:      true,
+			wantRoute:  "/s/:id",
+			wantParams: map[string]string{"id": "1"},
+		},
+		{
+			name:      "star matches the rest",
+			path:      "/s/1/2",
+			found:     true,
+			wantRoute: "/s/*",
+		},
+		{
+			name:      "star with children",
+			path:      "/w/1/abc",
+			found:     true,
+			wantRoute: "/w/*/abc",
+		},
+		{
+			// 通配符的子树匹配失败，通配符本身匹配剩下的所有段
+			name:      "star fallback",
+			path:      "/w/1/def",
+			found:     true,
+			wantRoute: "/w/*",
+		},
+		{
+			name: "not found",
+			path: "/p/12/z",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mi, found := r.findRoute(http.MethodGet, tc.path)
+			assert.Equal(t, tc.found, found)
+			if !found {
+				return
+			}
+			ctx := &Context{}
+			mi.node.handler(ctx)
+			assert.Equal(t, tc.wantRoute, string(ctx.RespData))
+			assert.Equal(t, tc.wantRoute, mi.node.route)
+			assert.Equal(t, tc.wantParams, mi.pathParams)
+		})
+	}
+}
+
+func Test_router_findRoute_namedWildcard(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/files/*filepath", mockHandler)
+	r.addRoute(http.MethodGet, "/users/*name/profile", mockHandler)
+	r.addRoute(http.MethodGet, "/static/*", mockHandler)
+
+	testCases := []struct {
+		name       string
+		path       string
+		found      bool
+		wantParams map[string]string
+	}{
+		{
+			name:       "catch-all one segment",
+			path:       "/files/a.css",
+			found:      true,
+			wantParams: map[string]string{"filepath": "a.css"},
+		},
+		{
+			name:       "catch-all multi segments",
+			path:       "/files/css/theme/a.css",
+			found:      true,
+			wantParams: map[string]string{"filepath": "css/theme/a.css"},
+		},
+		{
+			name:       "single segment",
+			path:       "/users/tom/profile",
+			found:      true,
+			wantParams: map[string]string{"name": "tom"},
+		},
+		{
+			// 中间的通配符没有 handler，不会匹配剩下的所有段
+			name: "single segment only",
+			path: "/users/tom/jerry/profile",
+		},
+		{
+			name:  "anonymous",
+			path:  "/static/js/a.js",
+			found: true,
+		},
+		{
+			name: "empty catch-all",
+			path: "/files",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mi, found := r.findRoute(http.MethodGet, tc.path)
+			assert.Equal(t, tc.found, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tc.wantParams, mi.pathParams)
+		})
+	}
+
+	assert.PanicsWithValue(t, "web: 路由冲突，通配符路由冲突，已有 *filepath，新注册 *path", func() {
+		r.addRoute(http.MethodGet, "/files/*path", mockHandler)
+	})
+}
@@ -0,0 +1,211 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo 描述一条注册过的路由
+type RouteInfo struct {
+	Method  string
+	Pattern string
+	Name    string
+	Handler string
+	// Middlewares 命中该路由时会执行的 middleware，不包含全局 middleware。
+	// 按照执行顺序排列：先是路径模式 middleware，再是路由级别 middleware
+	Middlewares []string
+}
+
+// RouteIssue 路由检查发现的问题，例如路由被其它路由遮蔽，永远无法命中
+type RouteIssue struct {
+	Method  string
+	Pattern string
+	Message string
+}
+
+// Routes 返回所有注册过的路由，按照路由模式和 HTTP 方法排序
+func (hs *HttpServer) Routes() []RouteInfo {
+	var res []RouteInfo
+	for method, root := range hs.router.trees {
+		root.walk(func(n *routeNode) {
+			if n.handler == nil {
+				return
+			}
+			info := RouteInfo{
+				Method:  method,
+				Pattern: n.route,
+				Name:    n.name,
+				Handler: funcName(n.handler),
+			}
+			for _, m := range hs.router.findMdls(root, routeSegs(n.route), true) {
+				info.Middlewares = append(info.Middlewares, funcName(m))
+			}
+			for _, m := range n.mdls {
+				info.Middlewares = append(info.Middlewares, funcName(m))
+			}
+			res = append(res, info)
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Pattern != res[j].Pattern {
+			return res[i].Pattern < res[j].Pattern
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+const maxRegexSamples = 4
+
+// regexSamples 检查正则路由时用来构造请求路径的候选值
+var regexSamples = []string{
+	"0", "1", "123", "-1", "1.5", "a", "z", "abc", "A", "Abc", "ABC",
+	"a1", "a-b", "a_b", "a.b", "true", "2006-01-02",
+	"123e4567-e89b-12d3-a456-426614174000",
+}
+
+// CheckRoutes 检查被其它路由遮蔽的路由。
+// 做法是按照路由模式构造请求路径，再看路由树实际命中的是不是它自己：
+// 参数段和通配符段直接用模式本身作为路径，正则段从 regexSamples 里面挑选匹配的值。
+// 正则段只要有一个候选值能够命中自己就认为可达，所以结果只是一个近似
+func (hs *HttpServer) CheckRoutes() []RouteIssue {
+	var res []RouteIssue
+	for _, info := range hs.Routes() {
+		probes, ok := routeProbes(info.Pattern)
+		if !ok {
+			res = append(res, RouteIssue{
+				Method:  info.Method,
+				Pattern: info.Pattern,
+				Message: "无法为正则段构造示例路径，跳过检查",
+			})
+			continue
+		}
+		var shadows []string
+		reachable := false
+		for _, probe := range probes {
+			mi, found := hs.router.findRoute(info.Method, probe)
+			if found && mi.node.route == info.Pattern {
+				reachable = true
+				break
+			}
+			if found {
+				shadows = append(shadows, mi.node.route)
+			}
+		}
+		if reachable {
+			continue
+		}
+		msg := "无法命中"
+		if len(shadows) > 0 {
+			msg = fmt.Sprintf("被 %s 遮蔽", shadows[0])
+		}
+		res = append(res, RouteIssue{
+			Method:  info.Method,
+			Pattern: info.Pattern,
+			Message: msg,
+		})
+	}
+	return res
+}
+
+// PrintRoutes 以表格的形式输出路由表，以及 CheckRoutes 发现的问题
+func (hs *HttpServer) PrintRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES")
+	for _, info := range hs.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", info.Method, info.Pattern,
+			info.Name, info.Handler, strings.Join(info.Middlewares, ","))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	issues := hs.CheckRoutes()
+	if len(issues) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n发现 %d 个问题：\n", len(issues)); err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		if _, err := fmt.Fprintf(w, "%s %s: %s\n", issue.Method, issue.Pattern, issue.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk 深度优先遍历子树，子节点的顺序是固定的
+func (rn *routeNode) walk(fn func(n *routeNode)) {
+	fn(rn)
+	keys := make([]string, 0, len(rn.children))
+	for k := range rn.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		rn.children[k].walk(fn)
+	}
+	for _, child := range rn.regChildren {
+		child.walk(fn)
+	}
+	if rn.paramChild != nil {
+		rn.paramChild.walk(fn)
+	}
+	if rn.starChild != nil {
+		rn.starChild.walk(fn)
+	}
+}
+
+// routeProbes 为路由模式构造用于检查的请求路径，正则段有多个候选值时会生成多条路径
+func routeProbes(pattern string) ([]string, bool) {
+	probes := []string{""}
+	for _, seg := range routeSegs(pattern) {
+		values := []string{seg}
+		if seg[0] == ':' {
+			if _, regPattern := getRegParam(seg); regPattern != "" {
+				expr := compileRegParam(seg, regPattern)
+				values = values[:0]
+				for _, sample := range regexSamples {
+					// 每个正则段最多取几个候选值，避免多个正则段的组合数量爆炸
+					if expr.MatchString(sample) && len(values) < maxRegexSamples {
+						values = append(values, sample)
+					}
+				}
+				if len(values) == 0 {
+					return nil, false
+				}
+			}
+		}
+		next := make([]string, 0, len(probes)*len(values))
+		for _, p := range probes {
+			for _, v := range values {
+				next = append(next, p+"/"+v)
+			}
+		}
+		probes = next
+	}
+	if len(probes) == 1 && probes[0] == "" {
+		return []string{"/"}, true
+	}
+	return probes, true
+}
+
+func routeSegs(pattern string) []string {
+	if pattern == "/" {
+		return nil
+	}
+	return strings.Split(pattern[1:], "/")
+}
+
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	return f.Name()
+}
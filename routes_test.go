@@ -0,0 +1,100 @@
+package web
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func routesTestHandler(ctx *Context) {}
+
+func routesTestAuth(next HandleFunc) HandleFunc { return next }
+
+func routesTestLog(next HandleFunc) HandleFunc { return next }
+
+func TestHttpServer_Routes(t *testing.T) {
+	s := NewHttpServer()
+	s.Use(http.MethodGet, "/admin/*", routesTestAuth)
+	s.AddRoute(http.MethodGet, "/", routesTestHandler)
+	s.AddNamedRoute("admin_user", http.MethodGet, "/admin/user/:id", routesTestHandler, routesTestLog)
+	s.AddRoute(http.MethodPost, "/admin/user", routesTestHandler)
+	s.AddRoute(http.MethodGet, "/files/*filepath", routesTestHandler)
+	s.Use(http.MethodGet, "/order/:id(\\d+)", routesTestLog)
+	s.AddRoute(http.MethodGet, "/order/:id(\\d+)", routesTestHandler)
+
+	assert.Equal(t, []RouteInfo{
+		{
+			Method:  http.MethodGet,
+			Pattern: "/",
+			Handler: "connor/go/web.routesTestHandler",
+		},
+		{
+			// POST 的路由树上没有注册 middleware
+			Method:  http.MethodPost,
+			Pattern: "/admin/user",
+			Handler: "connor/go/web.routesTestHandler",
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/admin/user/:id",
+			Name:    "admin_user",
+			Handler: "connor/go/web.routesTestHandler",
+			Middlewares: []string{
+				"connor/go/web.routesTestAuth",
+				"connor/go/web.routesTestLog",
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/files/*filepath",
+			Handler: "connor/go/web.routesTestHandler",
+		},
+		{
+			// 注册在正则段上的路径模式 middleware
+			Method:      http.MethodGet,
+			Pattern:     "/order/:id(\\d+)",
+			Handler:     "connor/go/web.routesTestHandler",
+			Middlewares: []string{"connor/go/web.routesTestLog"},
+		},
+	}, s.Routes())
+	assert.Empty(t, s.CheckRoutes())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, s.PrintRoutes(buf))
+	assert.Contains(t, buf.String(), "connor/go/web.routesTestAuth,connor/go/web.routesTestLog")
+}
+
+func TestHttpServer_CheckRoutes(t *testing.T) {
+	s := NewHttpServer()
+	// 先注册的正则优先，后面的正则能够匹配的值都会被前面的抢走
+	s.AddRoute(http.MethodGet, "/order/:id(\\d+)", routesTestHandler)
+	s.AddRoute(http.MethodGet, "/order/:no([0-9]+)", routesTestHandler)
+	// 正则能够匹配任意值，参数路由永远无法命中
+	s.AddRoute(http.MethodGet, "/user/:any(.+)", routesTestHandler)
+	s.AddRoute(http.MethodGet, "/user/:id", routesTestHandler)
+	// 正则的值有交集但是不完全覆盖，不算遮蔽
+	s.AddRoute(http.MethodGet, "/item/:id(\\d+)", routesTestHandler)
+	s.AddRoute(http.MethodGet, "/item/:code([0-9a-z]+)", routesTestHandler)
+	// 构造不出示例路径
+	s.AddRoute(http.MethodGet, "/weird/:id(#+)", routesTestHandler)
+
+	assert.Equal(t, []RouteIssue{
+		{
+			Method:  http.MethodGet,
+			Pattern: "/order/:no([0-9]+)",
+			Message: "被 /order/:id(\\d+) 遮蔽",
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/user/:id",
+			Message: "被 /user/:any(.+) 遮蔽",
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/weird/:id(#+)",
+			Message: "无法为正则段构造示例路径，跳过检查",
+		},
+	}, s.CheckRoutes())
+}
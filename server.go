@@ -1,30 +1,84 @@
 package web
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"net"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 var _ http.Handler = &HttpServer{}
 
+const (
+	// NotFoundRoute 没有命中任何路由时 Context.MatchedRoute 的值
+	NotFoundRoute = "NOT_FOUND"
+	// MethodNotAllowedRoute path 存在但是 HTTP 方法不匹配时 Context.MatchedRoute 的值
+	MethodNotAllowedRoute = "METHOD_NOT_ALLOWED"
+	// OptionsRoute 没有显式注册 OPTIONS 路由，框架自动响应 OPTIONS 请求时 Context.MatchedRoute 的值
+	OptionsRoute = "AUTO_OPTIONS"
+)
+
 type ServerOption func(server *HttpServer)
 
 type HttpServer struct {
 	router      *router
 	middlewares []Middleware
+	tplEngine   TemplateEngine
+	server      *http.Server
+	tlsConfig   *tls.Config
+	h2c         bool
+
+	// handlerTimeout 处理单个请求的超时时间，0 表示不限制
+	handlerTimeout time.Duration
+
+	onStart    []Hook
+	onShutdown []Hook
+
+	mutex sync.Mutex
+	// listeners 正在提供服务的 listener，热升级的时候传递给子进程
+	listeners []net.Listener
+	// readyPipe 热升级时父进程传递过来的管道，子进程就绪之后通过它通知父进程
+	readyPipe *os.File
+	// upgradeArgs 热升级时启动子进程的命令，默认为 os.Args
+	upgradeArgs []string
+
+	notFoundHandler         HandleFunc
+	methodNotAllowedHandler HandleFunc
+	bindErrorHandler        func(ctx *Context, err error)
+
+	// maxBodyBytes 请求体的大小限制，0 表示不限制
+	maxBodyBytes int64
+	strictJSON   bool
 }
 
 func NewHttpServer(opts ...ServerOption) *HttpServer {
 	server := &HttpServer{
-		router: newRouter(),
+		router:                  newRouter(),
+		notFoundHandler:         defaultNotFoundHandler,
+		methodNotAllowedHandler: defaultMethodNotAllowedHandler,
+	}
+	server.server = &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
 	}
 	if opts != nil {
 		for _, opt := range opts {
 			opt(server)
 		}
 	}
+	if server.h2c {
+		server.server.Handler = h2c.NewHandler(server, &http2.Server{})
+	}
 	return server
 }
 
@@ -34,13 +88,48 @@ func MiddlewaresOption(mids []Middleware) ServerOption {
 	}
 }
 
+// TemplateEngineOption 设置渲染页面用的模板引擎，Context.Render 依赖它
+func TemplateEngineOption(engine TemplateEngine) ServerOption {
+	return func(server *HttpServer) {
+		server.tplEngine = engine
+	}
+}
+
+// NotFoundHandlerOption 设置没有命中路由时的处理逻辑。
+// handler 依旧会经过全局的 middleware，此时 Context.MatchedRoute 为 NotFoundRoute
+func NotFoundHandlerOption(handler HandleFunc) ServerOption {
+	return func(server *HttpServer) {
+		server.notFoundHandler = handler
+	}
+}
+
+// MethodNotAllowedHandlerOption 设置 path 存在但 HTTP 方法不匹配时的处理逻辑。
+// 调用 handler 之前已经设置好了 Allow 响应头，此时 Context.MatchedRoute 为 MethodNotAllowedRoute
+func MethodNotAllowedHandlerOption(handler HandleFunc) ServerOption {
+	return func(server *HttpServer) {
+		server.methodNotAllowedHandler = handler
+	}
+}
+
 func (hs *HttpServer) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 	ctx := &Context{
-		Req:  request,
-		Resp: response,
+		Req:              request,
+		tplEngine:        hs.tplEngine,
+		router:           hs.router,
+		bindErrorHandler: hs.bindErrorHandler,
+		strictJSON:       hs.strictJSON,
+		notFoundHandler:  hs.notFoundHandler,
+	}
+	ctx.writer = &responseWriter{ResponseWriter: response, ctx: ctx}
+	ctx.Resp = ctx.writer
+	if hs.maxBodyBytes > 0 {
+		ctx.SetMaxBodyBytes(hs.maxBodyBytes)
 	}
 
 	middlewareChain := hs.serve
+	if hs.handlerTimeout > 0 {
+		middlewareChain = hs.serveWithTimeout
+	}
 
 	for i := len(hs.middlewares) - 1; i >= 0; i-- {
 		middlewareChain = hs.middlewares[i](middlewareChain)
@@ -48,6 +137,7 @@ func (hs *HttpServer) ServeHTTP(response http.ResponseWriter, request *http.Requ
 
 	final := func(next HandleFunc) HandleFunc {
 		return func(ctx *Context) {
+			defer ctx.removeTempFiles()
 			next(ctx)
 			hs.flashResp(ctx)
 		}
@@ -58,40 +148,197 @@ func (hs *HttpServer) ServeHTTP(response http.ResponseWriter, request *http.Requ
 	middlewareChain(ctx)
 }
 
-func (hs *HttpServer) Start(addr string) {
+// Start 监听 addr 并且提供服务，直到调用 Shutdown。
+// 调用 Shutdown 之后 Start 会立刻返回 nil，此时处理中的请求可能还没有结束
+func (hs *HttpServer) Start(addr string) error {
 	l, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("Listen Port Error: %v", err)
+		return err
+	}
+	return hs.Serve(l)
+}
+
+// Serve 在 l 上提供服务，直到调用 Shutdown。OnStart 回调会在开始接收请求之前执行，
+// 任何一个回调返回 error 都会关闭 l 并且返回该 error，后续的回调不再执行
+func (hs *HttpServer) Serve(l net.Listener) error {
+	return hs.ServeListeners(l)
+}
+
+// ServeListeners 同时在多个 listener 上提供服务，例如一个对外的端口和一个管理端口，
+// 直到调用 Shutdown。OnStart 回调只会执行一次。
+// 任何一个 listener 出错都会关闭所有的 listener，并且返回第一个 error
+func (hs *HttpServer) ServeListeners(ls ...net.Listener) error {
+	if len(ls) == 0 {
+		return errors.New("web: 没有可用的 listener")
+	}
+	return hs.runServer(ls, hs.server.Serve)
+}
+
+// runServer 执行 OnStart 回调，然后在每个 listener 上调用 serve 提供服务
+func (hs *HttpServer) runServer(ls []net.Listener, serve func(l net.Listener) error) error {
+	closeAll := func() {
+		for _, l := range ls {
+			_ = l.Close()
+		}
+	}
+	for _, hook := range hs.onStart {
+		if err := hook(context.Background()); err != nil {
+			closeAll()
+			return err
+		}
+	}
+
+	hs.mutex.Lock()
+	hs.listeners = append(hs.listeners, ls...)
+	hs.mutex.Unlock()
+	// 不管是 Shutdown 还是出错，返回的时候这些 listener 都已经关闭了
+	defer hs.removeListeners(ls)
+
+	errCh := make(chan error, len(ls))
+	for _, l := range ls {
+		fmt.Printf("Start Http Server At %s ...\n", l.Addr())
+		go func(l net.Listener) {
+			errCh <- serve(l)
+		}(l)
 	}
+	// 热升级启动的子进程，告诉父进程已经可以接收请求了
+	hs.notifyReady()
 
-	// 在这里可以添加前置逻辑
+	var err error
+	for range ls {
+		serveErr := <-errCh
+		if serveErr == nil || errors.Is(serveErr, http.ErrServerClosed) || err != nil {
+			continue
+		}
+		err = serveErr
+		closeAll()
+	}
+	return err
+}
 
-	fmt.Printf("Start Http Server At %s ...\n", addr)
-	if err := http.Serve(l, hs); err != nil {
-		log.Fatalf("Start Http Srver Error: %v", err)
+// removeListeners 从正在提供服务的 listener 里面去掉 ls
+func (hs *HttpServer) removeListeners(ls []net.Listener) {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	res := hs.listeners[:0]
+	for _, l := range hs.listeners {
+		removed := false
+		for _, rl := range ls {
+			if l == rl {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			res = append(res, l)
+		}
 	}
+	hs.listeners = res
+}
+
+// AddRoute 注册路由，mids 是路由级别的 middleware，只有命中该路由的请求才会执行
+func (hs *HttpServer) AddRoute(method string, path string, handler HandleFunc, mids ...Middleware) {
+	hs.router.addRoute(method, path, handler, mids...)
 }
 
-func (hs *HttpServer) AddRoute(method string, path string, handler HandleFunc) {
-	hs.router.addRoute(method, path, handler)
+// AddNamedRoute 注册具名路由，之后可以通过 URLFor 根据名字生成 URL。名字必须唯一
+func (hs *HttpServer) AddNamedRoute(name string, method string, path string, handler HandleFunc, mids ...Middleware) {
+	hs.router.addNamedRoute(name, method, path, handler, mids...)
+}
+
+// Use 在路径模式上注册 middleware，例如 Use(http.MethodGet, "/admin/*", auth)。
+// 以 * 结尾的模式作用于其下所有层级的路由，其余模式只作用于完全匹配的路由
+func (hs *HttpServer) Use(method string, path string, mids ...Middleware) {
+	hs.router.use(method, path, mids...)
 }
 
 func (hs *HttpServer) serve(ctx *Context) {
+	hs.findHandler(ctx)(ctx)
+}
+
+// findHandler 查找路由并设置好 PathParams 和 MatchedRoute，返回套上 middleware 之后的 handler。
+// 没有命中的时候返回 NotFound 或者 MethodNotAllowed 的处理逻辑
+func (hs *HttpServer) findHandler(ctx *Context) HandleFunc {
 	mi, ok := hs.router.findRoute(ctx.Req.Method, ctx.Req.URL.Path)
+	// 没有注册 HEAD 路由的时候交给 GET 路由处理，响应体由 flashResp 丢掉
+	if !ok && ctx.Req.Method == http.MethodHead {
+		mi, ok = hs.router.findRoute(http.MethodGet, ctx.Req.URL.Path)
+	}
 	if !ok || mi == nil || mi.node.handler == nil {
-		ctx.RespStatusCode = 404
-		ctx.RespData = []byte("NOT FOUND")
-		return
+		if methods := hs.router.findAllowedMethods(ctx.Req.URL.Path); len(methods) > 0 {
+			ctx.MatchedRoute = MethodNotAllowedRoute
+			if ctx.Req.Method == http.MethodOptions {
+				ctx.MatchedRoute = OptionsRoute
+			}
+			return func(ctx *Context) {
+				hs.methodNotAllowed(ctx, methods)
+			}
+		}
+		ctx.MatchedRoute = NotFoundRoute
+		return hs.notFoundHandler
 	}
 	ctx.PathParams = mi.pathParams
 	ctx.MatchedRoute = mi.node.route
-	mi.node.handler(ctx)
+
+	// 执行顺序：路径模式 middleware -> 路由级别 middleware -> handler
+	handler := mi.node.handler
+	for i := len(mi.node.mdls) - 1; i >= 0; i-- {
+		handler = mi.node.mdls[i](handler)
+	}
+	for i := len(mi.mdls) - 1; i >= 0; i-- {
+		handler = mi.mdls[i](handler)
+	}
+	return handler
+}
+
+// methodNotAllowed 处理 path 存在但是 HTTP 方法不匹配的请求。
+// OPTIONS 请求如果没有显式注册路由，会自动根据已注册的方法返回 Allow
+func (hs *HttpServer) methodNotAllowed(ctx *Context, methods []string) {
+	hasOptions := false
+	for _, method := range methods {
+		if method == http.MethodOptions {
+			hasOptions = true
+			break
+		}
+	}
+	if !hasOptions {
+		methods = append(methods, http.MethodOptions)
+		sort.Strings(methods)
+	}
+	ctx.Resp.Header().Set("Allow", strings.Join(methods, ", "))
+	if ctx.Req.Method == http.MethodOptions {
+		ctx.RespStatusCode = http.StatusNoContent
+		return
+	}
+	hs.methodNotAllowedHandler(ctx)
+}
+
+func defaultNotFoundHandler(ctx *Context) {
+	ctx.RespStatusCode = http.StatusNotFound
+	ctx.RespData = []byte("NOT FOUND")
+}
+
+func defaultMethodNotAllowedHandler(ctx *Context) {
+	ctx.RespStatusCode = http.StatusMethodNotAllowed
+	ctx.RespData = []byte("METHOD NOT ALLOWED")
 }
 
 func (hs *HttpServer) flashResp(ctx *Context) {
+	// handler 已经自己写了响应
+	if ctx.Written() {
+		return
+	}
+	// HEAD 请求只返回响应头，Content-Length 和 GET 请求保持一致
+	isHead := ctx.Req.Method == http.MethodHead
+	if isHead && len(ctx.RespData) > 0 && ctx.Resp.Header().Get("Content-Length") == "" {
+		ctx.Resp.Header().Set("Content-Length", strconv.Itoa(len(ctx.RespData)))
+	}
 	if ctx.RespStatusCode != 0 {
 		ctx.Resp.WriteHeader(ctx.RespStatusCode)
 	}
+	if isHead || len(ctx.RespData) == 0 {
+		return
+	}
 	n, err := ctx.Resp.Write(ctx.RespData)
 	if err != nil || n != len(ctx.RespData) {
 		fmt.Printf("写入响应失败 %v", err)
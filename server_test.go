@@ -0,0 +1,150 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHttpServer_methodNotAllowed(t *testing.T) {
+	mockHandler := func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("ok")
+	}
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/user/:id", mockHandler)
+	s.AddRoute(http.MethodPost, "/user/:id", mockHandler)
+	s.AddRoute(http.MethodDelete, "/user/*", mockHandler)
+	s.AddRoute(http.MethodGet, "/order", mockHandler)
+	s.AddRoute(http.MethodOptions, "/order", mockHandler)
+
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantAllow string
+		wantBody  string
+		wantLen   string
+	}{
+		{
+			name:     "found",
+			method:   http.MethodGet,
+			path:     "/user/123",
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:      "method not allowed",
+			method:    http.MethodPut,
+			path:      "/user/123",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "DELETE, GET, HEAD, OPTIONS, POST",
+			wantBody:  "METHOD NOT ALLOWED",
+		},
+		{
+			name:      "auto options",
+			method:    http.MethodOptions,
+			path:      "/user/123",
+			wantCode:  http.StatusNoContent,
+			wantAllow: "DELETE, GET, HEAD, OPTIONS, POST",
+		},
+		{
+			name:     "explicit options",
+			method:   http.MethodOptions,
+			path:     "/order",
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:      "explicit options in allow",
+			method:    http.MethodPost,
+			path:      "/order",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "GET, HEAD, OPTIONS",
+			wantBody:  "METHOD NOT ALLOWED",
+		},
+		{
+			// 没有注册 HEAD 的时候使用 GET 路由，只返回响应头
+			name:     "head falls back to get",
+			method:   http.MethodHead,
+			path:     "/user/123",
+			wantCode: http.StatusOK,
+			wantLen:  "2",
+		},
+		{
+			name:      "head without get",
+			method:    http.MethodHead,
+			path:      "/user/123/abc",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "DELETE, OPTIONS",
+			wantLen:   "18",
+		},
+		{
+			name:     "not found",
+			method:   http.MethodGet,
+			path:     "/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+		{
+			name:     "options not found",
+			method:   http.MethodOptions,
+			path:     "/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantLen, recorder.Header().Get("Content-Length"))
+		})
+	}
+}
+
+func TestHttpServer_customHandlers(t *testing.T) {
+	var routes []string
+	logMiddleware := func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			routes = append(routes, ctx.MatchedRoute)
+		}
+	}
+	s := NewHttpServer(
+		MiddlewaresOption([]Middleware{logMiddleware}),
+		NotFoundHandlerOption(func(ctx *Context) {
+			_ = ctx.RespJSON(http.StatusNotFound, map[string]string{"error": "not found"})
+		}),
+		MethodNotAllowedHandlerOption(func(ctx *Context) {
+			_ = ctx.RespJSON(http.StatusMethodNotAllowed, map[string]string{
+				"error": "method not allowed",
+				"allow": ctx.Resp.Header().Get("Allow"),
+			})
+		}),
+	)
+	s.AddRoute(http.MethodGet, "/user", func(ctx *Context) {})
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.JSONEq(t, `{"error":"not found"}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/user", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.JSONEq(t, `{"error":"method not allowed","allow":"GET, HEAD, OPTIONS"}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/user", nil))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+	assert.Equal(t, []string{NotFoundRoute, MethodNotAllowedRoute, OptionsRoute}, routes)
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event 一条 Server-Sent Events 事件
+type Event struct {
+	// ID 客户端断线重连的时候会通过 Last-Event-ID 请求头带回来
+	ID string
+	// Event 事件类型，为空的时候客户端按照 message 处理
+	Event string
+	// Data 事件内容，可以包含多行
+	Data string
+	// Retry 告诉客户端断线之后多久重连，0 表示不修改
+	Retry time.Duration
+}
+
+// SSE Server-Sent Events 连接，通过 Context.SSE 创建。
+// 事件会立刻发送给客户端，不经过 RespData 缓存
+type SSE struct {
+	ctx   *Context
+	mutex sync.Mutex
+}
+
+// SSE 把当前请求切换成 Server-Sent Events，马上发送响应头。
+// 之后 RespStatusCode 和 RespData 都不再生效，errhdl 之类的 middleware 也不会再改写响应
+func (c *Context) SSE() (*SSE, error) {
+	if c.Written() {
+		return nil, errors.New("web: 响应已经开始写入，无法切换成 SSE")
+	}
+	header := c.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 避免 nginx 之类的反向代理缓存事件
+	header.Set("X-Accel-Buffering", "no")
+	c.RespStatusCode = http.StatusOK
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+	return &SSE{ctx: c}, nil
+}
+
+// LastEventID 客户端断线重连时带上的最后一条事件的 ID，用于从断点继续推送
+func (s *SSE) LastEventID() string {
+	return s.ctx.Req.Header.Get("Last-Event-ID")
+}
+
+// Done 客户端断开连接之后会被关闭
+func (s *SSE) Done() <-chan struct{} {
+	return s.ctx.Req.Context().Done()
+}
+
+// Send 发送一条事件，客户端已经断开的时候返回 error
+func (s *SSE) Send(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n") || strings.ContainsAny(e.Event, "\r\n") {
+		return errors.New("web: SSE 事件的 ID 和 Event 不能包含换行")
+	}
+	var sb strings.Builder
+	if e.ID != "" {
+		sb.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		sb.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return s.write(sb.String())
+}
+
+// Ping 发送一条注释作为心跳，客户端会忽略它，但是可以防止中间的代理因为空闲断开连接
+func (s *SSE) Ping() error {
+	return s.write(": ping\n\n")
+}
+
+// Stream 持续发送 events 里面的事件，直到 events 被关闭或者客户端断开连接。
+// heartbeat 大于 0 的时候，每隔 heartbeat 没有事件就发送一次心跳
+func (s *SSE) Stream(events <-chan Event, heartbeat time.Duration) error {
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker = time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-s.Done():
+			return s.ctx.Req.Context().Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(e); err != nil {
+				return err
+			}
+			if ticker != nil {
+				ticker.Reset(heartbeat)
+			}
+		case <-tick:
+			if err := s.Ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *SSE) write(msg string) error {
+	if err := s.ctx.Req.Context().Err(); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.ctx.Write([]byte(msg)); err != nil {
+		return err
+	}
+	return s.ctx.Flush()
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_SSE(t *testing.T) {
+	var afterStatus int
+	s := NewHttpServer(MiddlewaresOption([]Middleware{
+		func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				next(ctx)
+				// 模拟 errhdl 改写响应，对 SSE 不生效
+				afterStatus = ctx.RespStatusCode
+				ctx.RespStatusCode = http.StatusInternalServerError
+				ctx.RespData = []byte("error page")
+			}
+		},
+	}))
+	s.AddRoute(http.MethodGet, "/events", func(ctx *Context) {
+		sse, err := ctx.SSE()
+		require.NoError(t, err)
+		events := make(chan Event, 3)
+		events <- Event{ID: "1", Event: "update", Data: "line1\nline2"}
+		events <- Event{Data: "resume from " + sse.LastEventID(), Retry: 3 * time.Second}
+		close(events)
+		assert.NoError(t, sse.Stream(events, 0))
+		assert.EqualError(t, sse.Send(Event{ID: "a\nb"}), "web: SSE 事件的 ID 和 Event 不能包含换行")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "42")
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, http.StatusOK, afterStatus)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, "id: 1\nevent: update\ndata: line1\ndata: line2\n\n"+
+		"retry: 3000\ndata: resume from 42\n\n", recorder.Body.String())
+}
+
+func TestContext_SSEHeartbeatAndDisconnect(t *testing.T) {
+	done := make(chan error, 1)
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/events", func(ctx *Context) {
+		sse, err := ctx.SSE()
+		require.NoError(t, err)
+		require.NoError(t, sse.Send(Event{Data: "hello"}))
+		// 一直没有事件，只有心跳
+		done <- sse.Stream(make(chan Event), 10*time.Millisecond)
+	})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+"/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 4 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	assert.Equal(t, []string{"data: hello", "", ": ping", ""}, lines)
+
+	// 客户端断开之后 Stream 返回
+	cancel()
+	select {
+	case err = <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("客户端断开之后 Stream 没有返回")
+	}
+}
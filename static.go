@@ -0,0 +1,291 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticOption 配置 StaticHandler
+type StaticOption func(h *StaticHandler)
+
+// StaticIndex 设置目录的默认文件，按顺序查找，默认为 index.html。不传参数表示不使用默认文件
+func StaticIndex(names ...string) StaticOption {
+	return func(h *StaticHandler) {
+		h.indexes = names
+	}
+}
+
+// StaticListDir 目录下没有默认文件的时候列出目录的内容。默认不列出，响应 404
+func StaticListDir() StaticOption {
+	return func(h *StaticHandler) {
+		h.listDir = true
+	}
+}
+
+// StaticMaxAge 设置 Cache-Control: public, max-age=n。默认不设置，由浏览器根据 ETag 和 Last-Modified 协商缓存
+func StaticMaxAge(d time.Duration) StaticOption {
+	return func(h *StaticHandler) {
+		h.maxAge = d
+	}
+}
+
+// StaticPathParam 设置文件路径所在的路径参数，默认为 filepath，对应路由 /static/*filepath
+func StaticPathParam(name string) StaticOption {
+	return func(h *StaticHandler) {
+		h.param = name
+	}
+}
+
+// StaticPrecompressed 客户端支持的时候，优先返回预先压缩好的 .br 和 .gz 文件，
+// 例如请求 app.js 时返回 app.js.br，Content-Type 依旧按照 app.js 设置
+func StaticPrecompressed() StaticOption {
+	return func(h *StaticHandler) {
+		h.precompressed = true
+	}
+}
+
+// precompressedEncodings 预压缩文件的编码和后缀，按照优先级排列
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{encoding: "br", ext: ".br"},
+	{encoding: "gzip", ext: ".gz"},
+}
+
+// StaticHandler 静态文件，需要注册在具名通配符路由上，例如：
+//
+//	h := web.NewStaticHandler("./public", web.StaticMaxAge(time.Hour))
+//	server.AddRoute(http.MethodGet, "/static/*filepath", h.Handle)
+//
+// 文件路径会先经过 path.Clean，再交给 fs.FS 打开，所以请求里面的 .. 不会跳出根目录。
+// 支持 ETag、Last-Modified 协商缓存以及 Range 请求，见 http.ServeContent。
+// 路由树不会把 /static/ 匹配到 /static/*filepath 上，需要访问根目录的话另外注册一个 /static 路由
+type StaticHandler struct {
+	fsys          fs.FS
+	indexes       []string
+	listDir       bool
+	maxAge        time.Duration
+	param         string
+	precompressed bool
+	// etags 没有修改时间的文件（例如 embed.FS）根据内容计算的 ETag，文件内容不会变，所以可以一直缓存
+	etags sync.Map
+}
+
+// NewStaticHandler 返回读取 dir 目录的 StaticHandler
+func NewStaticHandler(dir string, opts ...StaticOption) *StaticHandler {
+	return NewStaticFSHandler(os.DirFS(dir), opts...)
+}
+
+// NewStaticFSHandler 返回读取 fsys 的 StaticHandler，fsys 可以是 embed.FS。
+// embed.FS 需要用 fs.Sub 去掉前面的目录，例如 fs.Sub(assets, "public")
+func NewStaticFSHandler(fsys fs.FS, opts ...StaticOption) *StaticHandler {
+	h := &StaticHandler{
+		fsys:    fsys,
+		indexes: []string{"index.html"},
+		param:   "filepath",
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Handle 处理请求，文件不存在的时候响应 404
+func (h *StaticHandler) Handle(ctx *Context) {
+	name := strings.TrimPrefix(path.Clean("/"+ctx.PathParams[h.param]), "/")
+	if name == "" {
+		name = "."
+	}
+	// 反斜杠在 Windows 上是路径分隔符，直接拒绝
+	if !fs.ValidPath(name) || strings.Contains(name, "\\") {
+		h.notFound(ctx)
+		return
+	}
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	if !info.IsDir() {
+		h.serveFile(ctx, name, info)
+		return
+	}
+
+	// 目录必须以 / 结尾，否则页面里面的相对路径会出错
+	if reqPath := ctx.Req.URL.Path; !strings.HasSuffix(reqPath, "/") {
+		target := path.Base(reqPath) + "/"
+		if ctx.Req.URL.RawQuery != "" {
+			target += "?" + ctx.Req.URL.RawQuery
+		}
+		_ = ctx.Redirect(http.StatusMovedPermanently, target)
+		return
+	}
+	for _, index := range h.indexes {
+		indexName := path.Join(name, index)
+		indexInfo, err := fs.Stat(h.fsys, indexName)
+		if err == nil && !indexInfo.IsDir() {
+			h.serveFile(ctx, indexName, indexInfo)
+			return
+		}
+	}
+	if !h.listDir {
+		h.notFound(ctx)
+		return
+	}
+	h.serveDir(ctx, name)
+}
+
+func (h *StaticHandler) serveFile(ctx *Context, name string, info fs.FileInfo) {
+	header := ctx.Header()
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+	if h.maxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(h.maxAge/time.Second)))
+	}
+	if h.precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		for _, enc := range precompressedEncodings {
+			if !acceptsEncoding(ctx.Req.Header.Get("Accept-Encoding"), enc.encoding) {
+				continue
+			}
+			encInfo, err := fs.Stat(h.fsys, name+enc.ext)
+			if err != nil || encInfo.IsDir() {
+				continue
+			}
+			// 压缩文件的内容没法探测类型，没有扩展名对应的类型时按照二进制处理
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "application/octet-stream")
+			}
+			header.Set("Content-Encoding", enc.encoding)
+			h.serveContent(ctx, name+enc.ext, encInfo)
+			return
+		}
+	}
+	h.serveContent(ctx, name, info)
+}
+
+func (h *StaticHandler) serveContent(ctx *Context, name string, info fs.FileInfo) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	defer f.Close()
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			h.fail(ctx, err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	etag, err := h.etag(name, info, content)
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	ctx.Header().Set("Etag", etag)
+	http.ServeContent(ctx.Resp, ctx.Req, info.Name(), info.ModTime(), content)
+}
+
+// etag 有修改时间的文件用修改时间和大小，否则根据内容计算
+func (h *StaticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+	}
+	if etag, ok := h.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	hash := fnv.New64a()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := fmt.Sprintf(`"%x-%x"`, hash.Sum64(), info.Size())
+	h.etags.Store(name, etag)
+	return etag, nil
+}
+
+func (h *StaticHandler) serveDir(ctx *Context, name string) {
+	entries, err := fs.ReadDir(h.fsys, name)
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&sb, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(entryName))
+	}
+	sb.WriteString("</pre>\n")
+	ctx.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx.RespStatusCode = http.StatusOK
+	ctx.RespData = []byte(sb.String())
+}
+
+// fail 文件不存在和没有权限都响应 404，不暴露服务器上的文件信息
+func (h *StaticHandler) fail(ctx *Context, err error) {
+	ctx.Header().Del("Content-Encoding")
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		h.notFound(ctx)
+		return
+	}
+	ctx.RespStatusCode = http.StatusInternalServerError
+	ctx.RespData = []byte("INTERNAL SERVER ERROR")
+}
+
+// notFound 交给 NotFoundHandlerOption 设置的处理逻辑，和没有命中路由的响应保持一致，
+// 此时 Context.MatchedRoute 为 NotFoundRoute
+func (h *StaticHandler) notFound(ctx *Context) {
+	// 去掉为文件设置的响应头，避免 404 被缓存或者被当成文件内容
+	header := ctx.Header()
+	header.Del("Content-Type")
+	header.Del("Cache-Control")
+	ctx.MatchedRoute = NotFoundRoute
+	handler := ctx.notFoundHandler
+	if handler == nil {
+		handler = defaultNotFoundHandler
+	}
+	handler(ctx)
+}
+
+// acceptsEncoding 判断 Accept-Encoding 里面有没有 encoding，q=0 表示不接受
+func acceptsEncoding(header string, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		params = strings.TrimSpace(params)
+		if !strings.HasPrefix(params, "q=") {
+			return true
+		}
+		q, err := strconv.ParseFloat(params[len("q="):], 64)
+		return err == nil && q > 0
+	}
+	return false
+}
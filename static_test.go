@@ -0,0 +1,226 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticHandler(t *testing.T) {
+	modTime := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":        {Data: []byte("<h1>home</h1>")},
+		"css/app.css":       {Data: []byte("body{}"), ModTime: modTime},
+		"js/app.js":         {Data: []byte("console.log(1)")},
+		"js/app.js.gz":      {Data: []byte("gzip")},
+		"js/app.js.br":      {Data: []byte("br")},
+		"docs/a.txt":        {Data: []byte("a")},
+		"docs/<b>.txt":      {Data: []byte("b")},
+		"docs/sub/c.txt":    {Data: []byte("c")},
+		"empty/placeholder": {Data: []byte("")},
+	}
+
+	testCases := []struct {
+		name   string
+		opts   []StaticOption
+		path   string
+		header map[string]string
+
+		wantCode   int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:     "file",
+			path:     "/static/css/app.css",
+			wantCode: http.StatusOK,
+			wantBody: "body{}",
+			wantHeader: map[string]string{
+				"Content-Type":  "text/css; charset=utf-8",
+				"Last-Modified": modTime.Format(http.TimeFormat),
+				"Etag":          `"1719c8df9b5e0000-6"`,
+			},
+		},
+		{
+			name:     "if modified since",
+			path:     "/static/css/app.css",
+			header:   map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			// embed.FS 没有修改时间，ETag 根据内容计算
+			name:     "if none match",
+			path:     "/static/index.html",
+			header:   map[string]string{"If-None-Match": `"a98809aa80729cd3-d"`},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:       "range",
+			path:       "/static/js/app.js",
+			header:     map[string]string{"Range": "bytes=0-6"},
+			wantCode:   http.StatusPartialContent,
+			wantBody:   "console",
+			wantHeader: map[string]string{"Content-Range": "bytes 0-6/14"},
+		},
+		{
+			name:       "max age",
+			opts:       []StaticOption{StaticMaxAge(time.Hour)},
+			path:       "/static/css/app.css",
+			wantCode:   http.StatusOK,
+			wantBody:   "body{}",
+			wantHeader: map[string]string{"Cache-Control": "public, max-age=3600"},
+		},
+		{
+			name:     "index",
+			path:     "/static/",
+			wantCode: http.StatusOK,
+			wantBody: "<h1>home</h1>",
+		},
+		{
+			name:       "redirect dir",
+			path:       "/static/docs?a=b",
+			wantCode:   http.StatusMovedPermanently,
+			wantHeader: map[string]string{"Location": "docs/?a=b"},
+		},
+		{
+			name:     "no list dir",
+			path:     "/static/docs/",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+		{
+			name:     "list dir",
+			opts:     []StaticOption{StaticListDir()},
+			path:     "/static/docs/",
+			wantCode: http.StatusOK,
+			wantBody: "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n" +
+				"<a href=\"%3Cb%3E.txt\">&lt;b&gt;.txt</a>\n<a href=\"a.txt\">a.txt</a>\n<a href=\"sub/\">sub/</a>\n</pre>\n",
+			wantHeader: map[string]string{"Content-Type": "text/html; charset=utf-8"},
+		},
+		{
+			name:     "not found",
+			path:     "/static/css/none.css",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+		{
+			name:     "traversal",
+			path:     "/static/../../etc/passwd",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+		{
+			name:     "br",
+			opts:     []StaticOption{StaticPrecompressed()},
+			path:     "/static/js/app.js",
+			header:   map[string]string{"Accept-Encoding": "gzip, deflate, br"},
+			wantCode: http.StatusOK,
+			wantBody: "br",
+			wantHeader: map[string]string{
+				"Content-Encoding": "br",
+				"Content-Type":     "text/javascript; charset=utf-8",
+				"Vary":             "Accept-Encoding",
+			},
+		},
+		{
+			name:       "gzip",
+			opts:       []StaticOption{StaticPrecompressed()},
+			path:       "/static/js/app.js",
+			header:     map[string]string{"Accept-Encoding": "gzip, br;q=0"},
+			wantCode:   http.StatusOK,
+			wantBody:   "gzip",
+			wantHeader: map[string]string{"Content-Encoding": "gzip"},
+		},
+		{
+			name:       "no precompressed variant",
+			opts:       []StaticOption{StaticPrecompressed()},
+			path:       "/static/css/app.css",
+			header:     map[string]string{"Accept-Encoding": "gzip, br"},
+			wantCode:   http.StatusOK,
+			wantBody:   "body{}",
+			wantHeader: map[string]string{"Content-Encoding": "", "Vary": "Accept-Encoding"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHttpServer()
+			s.AddRoute(http.MethodGet, "/static/*filepath", NewStaticFSHandler(fsys, tc.opts...).Handle)
+			s.AddRoute(http.MethodGet, "/static", NewStaticFSHandler(fsys, tc.opts...).Handle)
+			req := httptest.NewRequest(http.MethodGet, "http://localhost"+tc.path, nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			for k, v := range tc.wantHeader {
+				assert.Equal(t, v, recorder.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestStaticHandler_notFoundHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/app.css": {Data: []byte("body{}")},
+	}
+	var matchedRoute string
+	s := NewHttpServer(NotFoundHandlerOption(func(ctx *Context) {
+		ctx.Header().Set("Content-Type", "application/json")
+		_ = ctx.RespJSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}), MiddlewaresOption([]Middleware{func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			matchedRoute = ctx.MatchedRoute
+		}
+	}}))
+	s.AddRoute(http.MethodGet, "/static/*filepath", NewStaticFSHandler(fsys, StaticMaxAge(time.Hour)).Handle)
+
+	for _, p := range []string{"/static/css/missing.css", "/static/css/"} {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, p, nil))
+		assert.Equal(t, http.StatusNotFound, recorder.Code, p)
+		assert.Equal(t, `{"error":"not found"}`, recorder.Body.String(), p)
+		assert.Empty(t, recorder.Header().Get("Cache-Control"), p)
+		assert.Equal(t, NotFoundRoute, matchedRoute, p)
+	}
+}
+
+func TestNewStaticHandler(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "public")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644))
+
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/files/*name", NewStaticHandler(dir, StaticPathParam("name")).Handle)
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/files/a.txt", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "a", recorder.Body.String())
+	assert.NotEmpty(t, recorder.Header().Get("Last-Modified"))
+	assert.NotEmpty(t, recorder.Header().Get("Etag"))
+
+	// HEAD 请求使用 GET 路由，只返回响应头
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "/files/a.txt", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("Content-Length"))
+	assert.Empty(t, recorder.Body.String())
+
+	// 请求路径没有经过 URL 规范化的时候，.. 也不能跳出根目录
+	req := httptest.NewRequest(http.MethodGet, "/files/a.txt", nil)
+	req.URL.Path = "/files/../secret.txt"
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -3,10 +3,17 @@ package main
 import (
 	"connor/go/web"
 	"connor/go/web/middleware/accesslog"
+	"flag"
+	"log"
 	"net/http"
+	"os"
+	"time"
 )
 
 func main() {
+	printRoutes := flag.Bool("routes", false, "打印路由表后退出")
+	flag.Parse()
+
 	middlewares := []web.Middleware{
 		accesslog.NewMiddleware(),
 	}
@@ -18,5 +25,14 @@ func main() {
 		ctx.RespData = []byte(ctx.PathParams["id"])
 	})
 
-	server.Start(":8080")
+	if *printRoutes {
+		if err := server.PrintRoutes(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := server.StartAndWait(":8080", 10*time.Second); err != nil {
+		log.Fatal(err)
+	}
 }
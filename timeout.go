@@ -0,0 +1,227 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultReadHeaderTimeout 默认的读取请求头超时时间，避免慢速攻击的连接一直占着不放
+const defaultReadHeaderTimeout = 10 * time.Second
+
+// ReadTimeoutOption 设置读取整个请求（包括请求体）的超时时间
+func ReadTimeoutOption(timeout time.Duration) ServerOption {
+	return func(server *HttpServer) {
+		server.server.ReadTimeout = timeout
+	}
+}
+
+// ReadHeaderTimeoutOption 设置读取请求头的超时时间，默认为 10 秒
+func ReadHeaderTimeoutOption(timeout time.Duration) ServerOption {
+	return func(server *HttpServer) {
+		server.server.ReadHeaderTimeout = timeout
+	}
+}
+
+// WriteTimeoutOption 设置从读完请求头到写完响应的超时时间
+func WriteTimeoutOption(timeout time.Duration) ServerOption {
+	return func(server *HttpServer) {
+		server.server.WriteTimeout = timeout
+	}
+}
+
+// IdleTimeoutOption 设置 keep-alive 连接在两个请求之间的最长空闲时间
+func IdleTimeoutOption(timeout time.Duration) ServerOption {
+	return func(server *HttpServer) {
+		server.server.IdleTimeout = timeout
+	}
+}
+
+// MaxHeaderBytesOption 设置请求头的最大字节数，超过的请求会收到 431
+func MaxHeaderBytesOption(n int) ServerOption {
+	return func(server *HttpServer) {
+		server.server.MaxHeaderBytes = n
+	}
+}
+
+// KeepAlivesOption 开启或者关闭 keep-alive，默认开启
+func KeepAlivesOption(enabled bool) ServerOption {
+	return func(server *HttpServer) {
+		server.server.SetKeepAlivesEnabled(enabled)
+	}
+}
+
+// HttpServerOption 直接修改底层的 http.Server，用于上面的 option 没有覆盖到的配置。
+// 不要修改 Handler
+func HttpServerOption(fn func(srv *http.Server)) ServerOption {
+	return func(server *HttpServer) {
+		fn(server.server)
+	}
+}
+
+// HandlerTimeoutOption 设置处理单个请求的超时时间。
+// 超时之后 ctx.Req.Context() 会被取消，并且立刻响应 503，不需要等 handler 返回。
+// handler 在单独的 goroutine 里面运行，框架不会强行中断它，超时之后它写入的响应会被丢弃，
+// 所以 handler 依旧应该监听 ctx.Req.Context() 并且尽快返回
+func HandlerTimeoutOption(timeout time.Duration) ServerOption {
+	return func(server *HttpServer) {
+		server.handlerTimeout = timeout
+	}
+}
+
+// serveWithTimeout 在单独的 goroutine 里面执行 serve，超时之后直接响应 503，和 http.TimeoutHandler 一样。
+// handler 使用 ctx 的副本，正常结束之后再把副本同步回 ctx，超时的 handler 不会再碰到 ctx。
+// 已经开始流式写入的响应没办法覆盖，只能等 handler 结束
+func (hs *HttpServer) serveWithTimeout(ctx *Context) {
+	reqCtx, cancel := context.WithTimeout(ctx.Req.Context(), hs.handlerTimeout)
+	defer cancel()
+
+	// 先查找路由，超时的时候 ctx 上也有 MatchedRoute 和 PathParams，middleware 可以按照路由统计
+	handler := hs.findHandler(ctx)
+	tw := &timeoutWriter{w: ctx.respWriter(), header: ctx.Header().Clone()}
+	hctx := *ctx
+	hctx.Req = ctx.Req.WithContext(reqCtx)
+	hctx.writer = &responseWriter{ResponseWriter: tw, ctx: &hctx}
+	hctx.Resp = hctx.writer
+
+	done := make(chan struct{})
+	panicChan := make(chan any, 1)
+	go func() {
+		defer func() {
+			p := recover()
+			// 超时或者 panic 的时候 hctx 不会同步回 ctx，handler 创建的临时文件只能在这里清理
+			if p != nil || tw.isTimedOut() {
+				hctx.removeTempFiles()
+			}
+			if p != nil {
+				panicChan <- p
+				return
+			}
+			close(done)
+		}()
+		handler(&hctx)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+	case <-reqCtx.Done():
+		if tw.timeout() {
+			ctx.Req = hctx.Req
+			ctx.RespStatusCode = http.StatusServiceUnavailable
+			ctx.RespData = []byte("SERVICE UNAVAILABLE")
+			return
+		}
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+		}
+	}
+
+	writer, resp := ctx.writer, ctx.Resp
+	*ctx = hctx
+	ctx.writer, ctx.Resp = writer, resp
+	if !tw.started {
+		replaceHeader(ctx.Header(), tw.header)
+	}
+}
+
+// timeoutWriter 超时控制下 handler 使用的 http.ResponseWriter。
+// 响应头先写在副本上，开始写入响应体之后才交给底层；超时之后的写入全部丢弃
+type timeoutWriter struct {
+	w      *responseWriter
+	header http.Header
+
+	mu       sync.Mutex
+	started  bool
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.started {
+		return tw.w.Header()
+	}
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.start() {
+		tw.w.WriteHeader(code)
+	}
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.start() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.w.Write(data)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.start() {
+		tw.w.Flush()
+	}
+}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.start() {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	return tw.w.Hijack()
+}
+
+// start 开始往底层写入，已经超时的时候返回 false。调用方需要持有锁
+func (tw *timeoutWriter) start() bool {
+	if tw.timedOut {
+		return false
+	}
+	if !tw.started {
+		tw.started = true
+		replaceHeader(tw.w.Header(), tw.header)
+	}
+	return true
+}
+
+// timeout 标记为超时，已经开始写入的时候返回 false
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.started {
+		return false
+	}
+	tw.timedOut = true
+	return true
+}
+
+func (tw *timeoutWriter) isTimedOut() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.timedOut
+}
+
+// replaceHeader 把 dst 替换成 src 的内容
+func replaceHeader(dst http.Header, src http.Header) {
+	for k := range dst {
+		if _, ok := src[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+}
@@ -0,0 +1,160 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHttpServer_HandlerTimeout(t *testing.T) {
+	var statuses []int
+	var routes []string
+	s := NewHttpServer(
+		HandlerTimeoutOption(50*time.Millisecond),
+		MiddlewaresOption([]Middleware{
+			func(next HandleFunc) HandleFunc {
+				return func(ctx *Context) {
+					next(ctx)
+					statuses = append(statuses, ctx.RespStatusCode)
+					routes = append(routes, ctx.MatchedRoute+" "+ctx.PathParams["id"])
+				}
+			},
+		}),
+	)
+	s.AddRoute(http.MethodGet, "/slow/:id", func(ctx *Context) {
+		<-ctx.Req.Context().Done()
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("too late")
+	})
+	s.AddRoute(http.MethodGet, "/fast", func(ctx *Context) {
+		_, hasDeadline := ctx.Req.Context().Deadline()
+		assert.True(t, hasDeadline)
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("ok")
+	})
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow/12", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "SERVICE UNAVAILABLE", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok", recorder.Body.String())
+
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusOK}, statuses)
+	// 超时的请求也能拿到命中的路由
+	assert.Equal(t, []string{"/slow/:id 12", "/fast "}, routes)
+}
+
+// TestHttpServer_HandlerTimeoutPanic handler panic 的时候 panic 交给外层的 middleware，临时文件也要删除
+func TestHttpServer_HandlerTimeoutPanic(t *testing.T) {
+	tempDir := t.TempDir()
+	s := NewHttpServer(
+		HandlerTimeoutOption(time.Second),
+		MiddlewaresOption([]Middleware{
+			func(next HandleFunc) HandleFunc {
+				return func(ctx *Context) {
+					defer func() {
+						if err := recover(); err != nil {
+							ctx.RespStatusCode = http.StatusInternalServerError
+							ctx.RespData = []byte(fmt.Sprint(err))
+						}
+					}()
+					next(ctx)
+				}
+			},
+		}),
+	)
+	s.AddRoute(http.MethodPost, "/upload", func(ctx *Context) {
+		_, err := ctx.FormFile("avatar", UploadTempDir(tempDir))
+		require.NoError(t, err)
+		panic("boom")
+	})
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, newMultipartRequest(t, nil, map[string][]byte{"avatar": []byte("hello")}))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "boom", recorder.Body.String())
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestHttpServer_HandlerTimeoutIgnoreContext handler 不监听 context 的时候，503 也要在超时的时候立刻发出去
+func TestHttpServer_HandlerTimeoutIgnoreContext(t *testing.T) {
+	s := NewHttpServer(HandlerTimeoutOption(50 * time.Millisecond))
+	lateWrite := make(chan error, 1)
+	s.AddRoute(http.MethodGet, "/slow", func(ctx *Context) {
+		time.Sleep(500 * time.Millisecond)
+		ctx.Header().Set("X-Late", "1")
+		_, err := ctx.Write([]byte("too late"))
+		lateWrite <- err
+	})
+
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "SERVICE UNAVAILABLE", recorder.Body.String())
+
+	// 超时之后 handler 写入的内容被丢弃
+	assert.ErrorIs(t, <-lateWrite, http.ErrHandlerTimeout)
+	assert.Equal(t, "SERVICE UNAVAILABLE", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("X-Late"))
+}
+
+func TestHttpServer_ConnOptions(t *testing.T) {
+	s := NewHttpServer(
+		ReadHeaderTimeoutOption(50*time.Millisecond),
+		MaxHeaderBytesOption(1024),
+		IdleTimeoutOption(time.Minute),
+		HttpServerOption(func(srv *http.Server) {
+			srv.WriteTimeout = time.Minute
+		}),
+	)
+	assert.Equal(t, time.Minute, s.server.IdleTimeout)
+	assert.Equal(t, time.Minute, s.server.WriteTimeout)
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = s.Serve(l)
+	}()
+	defer s.Shutdown(context.Background())
+
+	// 请求头一直发不完的连接会被关闭
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = io.ReadAll(conn)
+	assert.NoError(t, err)
+
+	// 请求头太大
+	conn, err = net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nX-Big: " +
+		strings.Repeat("a", 8192) + "\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
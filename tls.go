@@ -0,0 +1,133 @@
+package web
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval 两次检查证书文件是否变化的最小间隔
+var certCheckInterval = time.Second
+
+// TLSConfigOption 设置 TLS 配置。StartTLS 的证书文件为空时，直接使用 cfg 里面的证书
+func TLSConfigOption(cfg *tls.Config) ServerOption {
+	return func(server *HttpServer) {
+		server.tlsConfig = cfg
+	}
+}
+
+// H2COption 开启 h2c，也就是明文的 HTTP/2。
+// 一般用于 TLS 已经在前面的负载均衡终止，但是依旧希望使用 HTTP/2 的场景
+func H2COption() ServerOption {
+	return func(server *HttpServer) {
+		server.h2c = true
+	}
+}
+
+// StartTLS 监听 addr 并且提供 HTTPS 服务，客户端支持的话会自动使用 HTTP/2。
+// 证书文件在磁盘上被替换之后，新的连接会使用新的证书，已有的连接不受影响
+func (hs *HttpServer) StartTLS(addr string, certFile string, keyFile string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return hs.ServeTLS(l, certFile, keyFile)
+}
+
+// ServeTLS 在 l 上提供 HTTPS 服务，见 StartTLS
+func (hs *HttpServer) ServeTLS(l net.Listener, certFile string, keyFile string) error {
+	var cfg *tls.Config
+	if hs.tlsConfig != nil {
+		cfg = hs.tlsConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	if certFile != "" || keyFile != "" {
+		reloader, err := newCertReloader(certFile, keyFile)
+		if err != nil {
+			_ = l.Close()
+			return err
+		}
+		cfg.GetCertificate = reloader.GetCertificate
+	}
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		_ = l.Close()
+		return errors.New("web: 没有配置 TLS 证书")
+	}
+	hs.server.TLSConfig = cfg
+	return hs.runServer([]net.Listener{l}, func(l net.Listener) error {
+		return hs.server.ServeTLS(l, "", "")
+	})
+}
+
+// certReloader 在握手的时候检查证书文件有没有变化，变化了就重新加载
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex     sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	cr := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mutex.RLock()
+	cert, needCheck := cr.cert, time.Since(cr.lastCheck) >= certCheckInterval
+	cr.mutex.RUnlock()
+	if !needCheck {
+		return cert, nil
+	}
+
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	// 有可能别的握手已经检查过了
+	if time.Since(cr.lastCheck) < certCheckInterval {
+		return cr.cert, nil
+	}
+	if err := cr.reload(); err != nil {
+		// 加载失败的时候继续使用旧证书，有可能证书和私钥只替换了一个
+		fmt.Printf("web: 重新加载证书失败 %v\n", err)
+	}
+	return cr.cert, nil
+}
+
+// reload 文件的修改时间变化了才会重新加载，调用方需要持有写锁
+func (cr *certReloader) reload() error {
+	cr.lastCheck = time.Now()
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return err
+	}
+	if cr.cert != nil && certInfo.ModTime().Equal(cr.certMod) && keyInfo.ModTime().Equal(cr.keyMod) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+	cr.cert = &cert
+	cr.certMod = certInfo.ModTime()
+	cr.keyMod = keyInfo.ModTime()
+	return nil
+}
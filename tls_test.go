@@ -0,0 +1,122 @@
+package web
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHttpServer_ServeTLS(t *testing.T) {
+	certCheckInterval = 10 * time.Millisecond
+	defer func() {
+		certCheckInterval = time.Second
+	}()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, "first")
+
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte(ctx.Req.Proto)
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = s.ServeTLS(l, certFile, keyFile)
+	}()
+	defer s.Shutdown(context.Background())
+
+	get := func() (*http.Response, string) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		}}
+		resp, err := client.Get("https://" + l.Addr().String() + "/")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp, resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	resp, cn := get()
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "first", cn)
+
+	// 替换证书文件之后，新的连接使用新证书
+	time.Sleep(20 * time.Millisecond)
+	writeTestCert(t, certFile, keyFile, "second")
+	// 修改时间的精度可能不够，手动推进一下
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+	time.Sleep(20 * time.Millisecond)
+	_, cn = get()
+	assert.Equal(t, "second", cn)
+}
+
+func TestHttpServer_ServeTLSWithoutCert(t *testing.T) {
+	s := NewHttpServer()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.EqualError(t, s.ServeTLS(l, "", ""), "web: 没有配置 TLS 证书")
+}
+
+func TestHttpServer_H2C(t *testing.T) {
+	s := NewHttpServer(H2COption())
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = s.Serve(l)
+	}()
+	defer s.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + l.Addr().String() + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+}
+
+// writeTestCert 生成自签名证书，cn 用来区分不同的证书
+func writeTestCert(t *testing.T, certFile string, keyFile string, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
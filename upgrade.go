@@ -0,0 +1,187 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// upgradeFdsEnv 热升级时告诉子进程继承了多少个 listener。
+// listener 的文件描述符从 3 开始，紧接着的一个文件描述符是通知父进程就绪的管道
+const upgradeFdsEnv = "WEB_UPGRADE_FDS"
+
+// UpgradeCommandOption 设置热升级时启动新进程的命令，默认为 os.Args。
+// 例如新版本的二进制文件放在了别的路径
+func UpgradeCommandOption(args ...string) ServerOption {
+	return func(server *HttpServer) {
+		server.upgradeArgs = args
+	}
+}
+
+// ListenOrInherit 监听 addrs。如果当前进程是热升级启动的子进程，则直接使用父进程传递过来的 listener，
+// 此时 addrs 只用于校验数量。addr 以 unix: 开头时监听 unix domain socket，例如 unix:/tmp/web.sock
+func (hs *HttpServer) ListenOrInherit(addrs ...string) ([]net.Listener, error) {
+	val, ok := os.LookupEnv(upgradeFdsEnv)
+	if !ok {
+		return listenAll(addrs)
+	}
+	_ = os.Unsetenv(upgradeFdsEnv)
+	n, err := strconv.Atoi(val)
+	if err != nil || n != len(addrs) {
+		return nil, fmt.Errorf("web: 父进程传递了 %s 个 listener，需要 %d 个", val, len(addrs))
+	}
+	ls, err := filesListeners(listenFdsStart, n, addrs)
+	if err != nil {
+		return nil, err
+	}
+	hs.readyPipe = os.NewFile(uintptr(listenFdsStart+n), "ready")
+	return ls, nil
+}
+
+// Upgrade 热升级：启动新的进程，并且把当前所有的 listener 交给它。
+// 新进程就绪之后当前进程调用 Shutdown 优雅退出，整个过程不会丢弃连接。
+// ctx 同时约束等待新进程就绪和优雅退出。新进程没能就绪的时候会被杀掉，当前进程继续提供服务
+func (hs *HttpServer) Upgrade(ctx context.Context) error {
+	hs.mutex.Lock()
+	ls := hs.listeners
+	hs.mutex.Unlock()
+	if len(ls) == 0 {
+		return errors.New("web: 没有正在提供服务的 listener")
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyReader.Close()
+
+	args := hs.upgradeArgs
+	if len(args) == 0 {
+		args = os.Args
+	}
+	env := append(upgradeEnv(), upgradeFdsEnv+"="+strconv.Itoa(len(ls)))
+	proc, err := startUpgradeProcess(args, env, ls, readyWriter)
+	// 子进程已经持有了文件描述符，父进程这边的写端要关掉，否则子进程退出的时候读不到 EOF
+	_ = readyWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	readyCh := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, readErr := readyReader.Read(buf)
+		readyCh <- readErr
+	}()
+	select {
+	case err = <-readyCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = proc.Kill()
+		go func() {
+			_, _ = proc.Wait()
+		}()
+		return fmt.Errorf("web: 等待新进程就绪失败: %w", err)
+	}
+	// 子进程不归当前进程管理了，回收掉对应的资源
+	_ = proc.Release()
+
+	for _, l := range ls {
+		// unix socket 默认会在 Close 的时候删除文件，而新进程还在用
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	return hs.Shutdown(ctx)
+}
+
+// StartUpgradable 在 addrs 上提供服务，收到 SIGINT 或 SIGTERM 之后在 timeout 内优雅退出；
+// 收到 SIGHUP 之后调用 Upgrade 热升级，热升级失败会继续提供服务
+func (hs *HttpServer) StartUpgradable(timeout time.Duration, addrs ...string) error {
+	ls, err := hs.ListenOrInherit(addrs...)
+	if err != nil {
+		return err
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- hs.ServeListeners(ls...)
+	}()
+	for {
+		select {
+		case err = <-errCh:
+			return err
+		case sig := <-sigCh:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			if sig == syscall.SIGHUP {
+				err = hs.Upgrade(ctx)
+				cancel()
+				if err != nil {
+					fmt.Printf("web: 热升级失败 %v\n", err)
+					continue
+				}
+				return nil
+			}
+			err = hs.Shutdown(ctx)
+			cancel()
+			return err
+		}
+	}
+}
+
+// notifyReady 热升级启动的子进程通知父进程自己已经就绪
+func (hs *HttpServer) notifyReady() {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	if hs.readyPipe == nil {
+		return
+	}
+	_, _ = hs.readyPipe.Write([]byte{1})
+	_ = hs.readyPipe.Close()
+	hs.readyPipe = nil
+}
+
+// upgradeEnv 子进程的环境变量，去掉当前进程继承来的热升级变量
+func upgradeEnv() []string {
+	env := os.Environ()
+	res := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, upgradeFdsEnv+"=") {
+			res = append(res, kv)
+		}
+	}
+	return res
+}
+
+func listenAll(addrs []string) ([]net.Listener, error) {
+	ls := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		var l net.Listener
+		var err error
+		if strings.HasPrefix(addr, "unix:") {
+			l, err = ListenUnix(strings.TrimPrefix(addr, "unix:"))
+		} else {
+			l, err = net.Listen("tcp", addr)
+		}
+		if err != nil {
+			for _, opened := range ls {
+				_ = opened.Close()
+			}
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package web
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+func startUpgradeProcess(args []string, env []string, ls []net.Listener, ready *os.File) (*os.Process, error) {
+	return nil, errors.New("web: 当前平台不支持热升级")
+}
@@ -0,0 +1,147 @@
+//go:build unix
+
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestUpgradeHelperProcess 不是真正的测试，而是热升级时启动的子进程
+func TestUpgradeHelperProcess(t *testing.T) {
+	switch os.Getenv("WEB_UPGRADE_TEST_HELPER") {
+	case "1":
+	case "fail":
+		// 模拟新版本启动失败，没有通知就绪就退出了
+		os.Exit(1)
+	default:
+		return
+	}
+	// 避免测试失败的时候子进程一直留着
+	time.AfterFunc(10*time.Second, func() {
+		os.Exit(1)
+	})
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("child")
+	})
+	s.AddRoute(http.MethodGet, "/exit", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		go func() {
+			_ = s.Shutdown(context.Background())
+		}()
+	})
+	ls, err := s.ListenOrInherit("127.0.0.1:0")
+	if err != nil {
+		os.Exit(2)
+	}
+	_ = s.ServeListeners(ls...)
+	os.Exit(0)
+}
+
+func TestHttpServer_Upgrade(t *testing.T) {
+	t.Setenv("WEB_UPGRADE_TEST_HELPER", "1")
+	s := NewHttpServer(UpgradeCommandOption(os.Args[0], "-test.run=^TestUpgradeHelperProcess$"))
+	handling := make(chan struct{})
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("parent")
+	})
+	s.AddRoute(http.MethodGet, "/slow", func(ctx *Context) {
+		close(handling)
+		time.Sleep(300 * time.Millisecond)
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte("parent slow")
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(l)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(path string) (string, error) {
+		resp, err := client.Get("http://" + l.Addr().String() + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+	body, err := get("/")
+	require.NoError(t, err)
+	assert.Equal(t, "parent", body)
+
+	type result struct {
+		body string
+		err  error
+	}
+	slowCh := make(chan result, 1)
+	go func() {
+		body, err := get("/slow")
+		slowCh <- result{body: body, err: err}
+	}()
+	<-handling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Upgrade(ctx))
+	assert.NoError(t, <-serveErr)
+
+	// 升级过程中处理的请求没有被丢弃
+	res := <-slowCh
+	require.NoError(t, res.err)
+	assert.Equal(t, "parent slow", res.body)
+
+	// 同一个端口现在由子进程提供服务
+	body, err = get("/")
+	require.NoError(t, err)
+	assert.Equal(t, "child", body)
+	_, err = get("/exit")
+	require.NoError(t, err)
+}
+
+func TestHttpServer_UpgradeChildFailed(t *testing.T) {
+	t.Setenv("WEB_UPGRADE_TEST_HELPER", "fail")
+	s := NewHttpServer(UpgradeCommandOption(os.Args[0], "-test.run=^TestUpgradeHelperProcess$"))
+	s.AddRoute(http.MethodGet, "/", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusOK
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(l)
+	}()
+	get := func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	// 请求能得到响应，说明 Serve 已经登记好了 listener
+	get()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.EqualError(t, s.Upgrade(ctx), "web: 等待新进程就绪失败: EOF")
+
+	// 当前进程继续提供服务
+	get()
+
+	// 停止之后没有可以交给新进程的 listener 了
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.NoError(t, <-serveErr)
+	assert.EqualError(t, s.Upgrade(ctx), "web: 没有正在提供服务的 listener")
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package web
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// startUpgradeProcess 启动热升级的子进程，子进程的文件描述符依次为：
+// 标准输入输出、ls 对应的 listener、ready 管道。
+// 这里没有使用 os/exec，因为它会调用 File.Fd 把 socket 切换成阻塞模式，
+// 而 socket 的阻塞标记是父子进程共享的，父进程的 Accept 会因此卡死在系统调用里
+func startUpgradeProcess(args []string, env []string, ls []net.Listener, ready *os.File) (*os.Process, error) {
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+	files := []uintptr{os.Stdin.Fd(), os.Stdout.Fd(), os.Stderr.Fd()}
+	for _, l := range ls {
+		sc, ok := l.(syscall.Conn)
+		if !ok {
+			return nil, fmt.Errorf("web: listener %s 不支持热升级", l.Addr())
+		}
+		rc, err := sc.SyscallConn()
+		if err != nil {
+			return nil, err
+		}
+		var fd uintptr
+		if err = rc.Control(func(f uintptr) {
+			fd = f
+		}); err != nil {
+			return nil, err
+		}
+		files = append(files, fd)
+	}
+	files = append(files, ready.Fd())
+	pid, err := syscall.ForkExec(path, args, &syscall.ProcAttr{
+		Env:   env,
+		Files: files,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return os.FindProcess(pid)
+}
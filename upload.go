@@ -0,0 +1,344 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// sniffLen http.DetectContentType 最多只看前 512 个字节
+const sniffLen = 512
+
+// defaultMaxValueSize 普通表单字段默认的大小上限，和 http.Request.ParseMultipartForm 一样是 10MB
+const defaultMaxValueSize = 10 << 20
+
+// UploadOption 配置文件上传
+type UploadOption func(cfg *uploadConfig)
+
+type uploadConfig struct {
+	maxFileSize  int64
+	maxTotalSize int64
+	maxValueSize int64
+	allowedTypes []string
+	tempDir      string
+}
+
+// UploadMaxFileSize 限制单个文件的大小，超过之后响应 413。默认不限制
+func UploadMaxFileSize(n int64) UploadOption {
+	return func(cfg *uploadConfig) {
+		cfg.maxFileSize = n
+	}
+}
+
+// UploadMaxTotalSize 限制所有文件和表单字段加起来的大小，超过之后响应 413。默认不限制
+func UploadMaxTotalSize(n int64) UploadOption {
+	return func(cfg *uploadConfig) {
+		cfg.maxTotalSize = n
+	}
+}
+
+// UploadMaxValueSize 限制所有普通表单字段加起来的大小，超过之后响应 413。
+// 表单字段的值会读到内存里面，所以默认限制为 10MB
+func UploadMaxValueSize(n int64) UploadOption {
+	return func(cfg *uploadConfig) {
+		cfg.maxValueSize = n
+	}
+}
+
+// UploadAllowedTypes 限制文件的类型，例如 "image/png"、"image/*"，不符合的时候响应 415。
+// 类型是根据文件内容探测出来的，不相信客户端声明的 Content-Type
+func UploadAllowedTypes(types ...string) UploadOption {
+	return func(cfg *uploadConfig) {
+		cfg.allowedTypes = types
+	}
+}
+
+// UploadTempDir 设置临时文件的目录，默认为 os.TempDir()
+func UploadTempDir(dir string) UploadOption {
+	return func(cfg *uploadConfig) {
+		cfg.tempDir = dir
+	}
+}
+
+// UploadedFile 上传的文件
+type UploadedFile struct {
+	Field    string
+	Filename string
+	// ContentType 根据文件内容探测出来的类型
+	ContentType string
+	Size        int64
+	// Path 临时文件的路径，写到调用方提供的 io.Writer 时为空。
+	// 请求结束之后临时文件会被删除，需要保留的话在 handler 里面移走
+	Path string
+}
+
+// Open 打开临时文件
+func (f *UploadedFile) Open() (*os.File, error) {
+	if f.Path == "" {
+		return nil, errors.New("web: 上传文件没有保存到临时文件")
+	}
+	return os.Open(f.Path)
+}
+
+// FormFile 返回表单里面 field 对应的第一个文件，没有的时候返回 http.ErrMissingFile。见 FormFiles
+func (c *Context) FormFile(field string, opts ...UploadOption) (*UploadedFile, error) {
+	files, err := c.FormFiles(field, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// FormFiles 返回表单里面 field 对应的所有文件。
+// 第一次调用的时候会以流的方式读取整个 multipart 请求体，所有的文件都写到临时文件里面，
+// 普通的表单字段之后可以通过 FormValue 读取。opts 只在第一次调用的时候生效
+func (c *Context) FormFiles(field string, opts ...UploadOption) ([]*UploadedFile, error) {
+	if c.uploads == nil {
+		if err := c.parseUploads(opts); err != nil {
+			return nil, err
+		}
+	}
+	return c.uploads[field], nil
+}
+
+func (c *Context) parseUploads(opts []UploadOption) error {
+	reader, err := c.MultipartReader(opts...)
+	if err != nil {
+		return err
+	}
+	uploads := make(map[string][]*UploadedFile)
+	values := make(url.Values)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if part.FileName() == "" {
+			val, err := part.Value()
+			if err != nil {
+				return err
+			}
+			values.Add(part.FormName(), val)
+			continue
+		}
+		file, err := part.SaveTemp()
+		if err != nil {
+			return err
+		}
+		uploads[file.Field] = append(uploads[file.Field], file)
+	}
+	c.uploads = uploads
+	// 和 ParseForm 的结果保持一致，之后 FormValue 也能读到 multipart 里面的字段
+	c.Req.PostForm = values
+	form := make(url.Values, len(values))
+	for k, v := range values {
+		form[k] = append(form[k], v...)
+	}
+	for k, v := range c.query() {
+		form[k] = append(form[k], v...)
+	}
+	c.Req.Form = form
+	return nil
+}
+
+// MultipartReader 以流的方式逐个读取 multipart 请求体的各个部分，适合需要自己决定文件写到哪里的场景，
+// 例如直接写到对象存储。大小和类型的限制依旧生效
+func (c *Context) MultipartReader(opts ...UploadOption) (*MultipartReader, error) {
+	cfg := uploadConfig{tempDir: os.TempDir(), maxValueSize: defaultMaxValueSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if c.bodyCached {
+		c.resetBody()
+	}
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		c.RespStatusCode = http.StatusBadRequest
+		return nil, fmt.Errorf("web: 读取 multipart 请求体失败: %w", err)
+	}
+	return &MultipartReader{
+		ctx:            c,
+		reader:         reader,
+		cfg:            cfg,
+		remaining:      cfg.maxTotalSize,
+		valueRemaining: cfg.maxValueSize,
+	}, nil
+}
+
+// MultipartReader 逐个读取 multipart 请求体的各个部分
+type MultipartReader struct {
+	ctx    *Context
+	reader *multipart.Reader
+	cfg    uploadConfig
+	// remaining 总大小限制剩下的字节数，cfg.maxTotalSize 为 0 的时候不使用
+	remaining int64
+	// valueRemaining 普通表单字段还能读取的字节数，cfg.maxValueSize 为 0 的时候不使用
+	valueRemaining int64
+}
+
+// NextPart 返回下一个部分，没有的时候返回 io.EOF
+func (r *MultipartReader) NextPart() (*Part, error) {
+	p, err := r.reader.NextPart()
+	if err != nil {
+		if err != io.EOF {
+			err = r.fail(err)
+		}
+		return nil, err
+	}
+	return &Part{Part: p, r: r}, nil
+}
+
+// fail 根据错误的类型设置响应的状态码
+func (r *MultipartReader) fail(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, errUploadTooLarge), errors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, errUploadType):
+		status = http.StatusUnsupportedMediaType
+	}
+	r.ctx.RespStatusCode = status
+	r.ctx.RespData = []byte(strings.ToUpper(http.StatusText(status)))
+	return err
+}
+
+var (
+	errUploadTooLarge = errors.New("超过了大小限制")
+	errUploadType     = errors.New("类型不被允许")
+)
+
+// Part multipart 请求体的一个部分，可能是文件，也可能是普通的表单字段
+type Part struct {
+	*multipart.Part
+	r *MultipartReader
+}
+
+// Read 读取内容，受总大小限制的约束
+func (p *Part) Read(data []byte) (int, error) {
+	if p.r.cfg.maxTotalSize <= 0 {
+		return p.Part.Read(data)
+	}
+	if p.r.remaining <= 0 {
+		// 看一下是不是刚好读完
+		var one [1]byte
+		if n, err := p.Part.Read(one[:]); n == 0 && err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, fmt.Errorf("web: 上传内容%w [%d 字节]", errUploadTooLarge, p.r.cfg.maxTotalSize)
+	}
+	if int64(len(data)) > p.r.remaining {
+		data = data[:p.r.remaining]
+	}
+	n, err := p.Part.Read(data)
+	p.r.remaining -= int64(n)
+	return n, err
+}
+
+// Value 读取普通表单字段的值，受 UploadMaxValueSize 的约束
+func (p *Part) Value() (string, error) {
+	var src io.Reader = p
+	limit := p.r.cfg.maxValueSize
+	if limit > 0 {
+		// 多读一个字节，用来判断是不是超过了限制
+		src = io.LimitReader(p, p.r.valueRemaining+1)
+	}
+	var sb strings.Builder
+	n, err := io.Copy(&sb, src)
+	if err != nil {
+		return "", p.r.fail(err)
+	}
+	if limit > 0 {
+		if n > p.r.valueRemaining {
+			return "", p.r.fail(fmt.Errorf("web: 表单字段%w [%d 字节]", errUploadTooLarge, limit))
+		}
+		p.r.valueRemaining -= n
+	}
+	return sb.String(), nil
+}
+
+// Stream 把文件内容写到 w，同时检查大小和类型
+func (p *Part) Stream(w io.Writer) (*UploadedFile, error) {
+	file := &UploadedFile{
+		Field:    p.FormName(),
+		Filename: p.FileName(),
+	}
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(p, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, p.r.fail(err)
+	}
+	head = head[:n]
+	file.ContentType = http.DetectContentType(head)
+	if !p.r.typeAllowed(file.ContentType) {
+		return nil, p.r.fail(fmt.Errorf("web: 上传文件 %s 的%w [%s]", file.Filename, errUploadType, file.ContentType))
+	}
+
+	var src io.Reader = p
+	if limit := p.r.cfg.maxFileSize; limit > 0 {
+		// 多读一个字节，用来判断是不是超过了限制
+		src = io.LimitReader(p, limit-int64(len(head))+1)
+	}
+	if _, err = w.Write(head); err != nil {
+		return nil, err
+	}
+	written, err := io.Copy(w, src)
+	if err != nil {
+		return nil, p.r.fail(err)
+	}
+	file.Size = int64(len(head)) + written
+	if limit := p.r.cfg.maxFileSize; limit > 0 && file.Size > limit {
+		return nil, p.r.fail(fmt.Errorf("web: 上传文件 %s %w [%d 字节]", file.Filename, errUploadTooLarge, limit))
+	}
+	return file, nil
+}
+
+// SaveTemp 把文件内容写到临时文件，请求结束之后临时文件会被删除
+func (p *Part) SaveTemp() (*UploadedFile, error) {
+	f, err := os.CreateTemp(p.r.cfg.tempDir, "web-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	p.r.ctx.tempFiles = append(p.r.ctx.tempFiles, f.Name())
+	file, err := p.Stream(f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	file.Path = f.Name()
+	return file, nil
+}
+
+func (r *MultipartReader) typeAllowed(contentType string) bool {
+	if len(r.cfg.allowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for _, t := range r.cfg.allowedTypes {
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeTempFiles 删除请求过程中创建的临时文件
+func (c *Context) removeTempFiles() {
+	for _, name := range c.tempFiles {
+		_ = os.Remove(name)
+	}
+	c.tempFiles = nil
+}
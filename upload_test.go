@@ -0,0 +1,180 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// pngHeader PNG 文件头，足够让 http.DetectContentType 识别出 image/png
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string][]byte) *http.Request {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range fields {
+		require.NoError(t, mw.WriteField(k, v))
+	}
+	for name, data := range files {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		require.NoError(t, err)
+		_, err = fw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+	req := httptest.NewRequest(http.MethodPost, "/upload?from=query", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestContext_FormFile(t *testing.T) {
+	tempDir := t.TempDir()
+	png := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 100)...)
+
+	testCases := []struct {
+		name     string
+		opts     []UploadOption
+		fields   map[string]string
+		files    map[string][]byte
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "ok",
+			opts:     []UploadOption{UploadMaxFileSize(108), UploadAllowedTypes("image/*")},
+			fields:   map[string]string{"name": "Tom"},
+			files:    map[string][]byte{"avatar": png},
+			wantCode: http.StatusOK,
+			wantBody: "Tom query avatar.bin image/png 108",
+		},
+		{
+			name:     "missing",
+			fields:   map[string]string{"name": "Tom"},
+			wantCode: http.StatusBadRequest,
+			wantBody: http.ErrMissingFile.Error(),
+		},
+		{
+			name:     "file too large",
+			opts:     []UploadOption{UploadMaxFileSize(107)},
+			files:    map[string][]byte{"avatar": png},
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantBody: "web: 上传文件 avatar.bin 超过了大小限制 [107 字节]",
+		},
+		{
+			name:     "total too large",
+			opts:     []UploadOption{UploadMaxTotalSize(110)},
+			fields:   map[string]string{"name": "Tom"},
+			files:    map[string][]byte{"avatar": png},
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantBody: "web: 上传内容超过了大小限制 [110 字节]",
+		},
+		{
+			// 表单字段默认有 10MB 的限制
+			name:     "value too large",
+			fields:   map[string]string{"name": strings.Repeat("a", defaultMaxValueSize+1)},
+			files:    map[string][]byte{"avatar": png},
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantBody: "web: 表单字段超过了大小限制 [10485760 字节]",
+		},
+		{
+			name:     "values too large",
+			opts:     []UploadOption{UploadMaxValueSize(5)},
+			fields:   map[string]string{"name": "Tom", "nick": "Jerry"},
+			files:    map[string][]byte{"avatar": png},
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantBody: "web: 表单字段超过了大小限制 [5 字节]",
+		},
+		{
+			// 类型根据内容探测，不看客户端声明的 Content-Type
+			name:     "type not allowed",
+			opts:     []UploadOption{UploadAllowedTypes("image/png")},
+			files:    map[string][]byte{"avatar": []byte("hello")},
+			wantCode: http.StatusUnsupportedMediaType,
+			wantBody: "web: 上传文件 avatar.bin 的类型不被允许 [text/plain; charset=utf-8]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var tempFile string
+			s := NewHttpServer()
+			s.AddRoute(http.MethodPost, "/upload", func(ctx *Context) {
+				file, err := ctx.FormFile("avatar", append(tc.opts, UploadTempDir(tempDir))...)
+				if err != nil {
+					if ctx.RespStatusCode == 0 {
+						ctx.RespStatusCode = http.StatusBadRequest
+					}
+					ctx.RespData = []byte(err.Error())
+					return
+				}
+				tempFile = file.Path
+				f, err := file.Open()
+				require.NoError(t, err)
+				defer f.Close()
+				data, err := io.ReadAll(f)
+				require.NoError(t, err)
+				assert.Equal(t, png, data)
+
+				name, _ := ctx.FormValue("name").String()
+				from, _ := ctx.FormValue("from").String()
+				ctx.RespStatusCode = http.StatusOK
+				ctx.RespData = []byte(fmt.Sprintf("%s %s %s %s %d", name, from, file.Filename, file.ContentType, file.Size))
+			})
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, newMultipartRequest(t, tc.fields, tc.files))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+
+			// 请求结束之后临时文件都被删除了
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+			if tempFile != "" {
+				_, err = os.Stat(tempFile)
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
+
+func TestContext_MultipartReader(t *testing.T) {
+	s := NewHttpServer()
+	var got bytes.Buffer
+	s.AddRoute(http.MethodPost, "/upload", func(ctx *Context) {
+		reader, err := ctx.MultipartReader(UploadMaxFileSize(1024))
+		require.NoError(t, err)
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			// 直接写到调用方提供的 io.Writer，不产生临时文件
+			file, err := part.Stream(&got)
+			require.NoError(t, err)
+			assert.Equal(t, "", file.Path)
+			assert.Equal(t, int64(5), file.Size)
+		}
+		ctx.RespStatusCode = http.StatusOK
+	})
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, newMultipartRequest(t, nil, map[string][]byte{"doc": []byte("hello")}))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "hello", got.String())
+
+	// 不是 multipart 请求
+	recorder = httptest.NewRecorder()
+	s.AddRoute(http.MethodPost, "/plain", func(ctx *Context) {
+		_, err := ctx.MultipartReader()
+		assert.EqualError(t, err, "web: 读取 multipart 请求体失败: request Content-Type isn't multipart/form-data")
+	})
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/plain", strings.NewReader("a=b")))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// namedRoute 具名路由，注册的时候就把路由拆好段，避免每次生成 URL 都重新解析
+type namedRoute struct {
+	route string
+	segs  []string
+	// 正则路由段的下标到正则的映射
+	exprs map[int]*regexp.Regexp
+}
+
+// addNamedRoute 注册具名路由，名字在整个 router 内必须唯一
+func (r *router) addNamedRoute(name string, method string, path string, handler HandleFunc, mdls ...Middleware) {
+	if name == "" {
+		panic("web: 路由名字是空字符串")
+	}
+	if _, ok := r.names[name]; ok {
+		panic(fmt.Sprintf("web: 路由名字冲突[%s]", name))
+	}
+	r.addRoute(method, path, handler, mdls...)
+	r.findOrCreateNode(method, path).name = name
+
+	nr := &namedRoute{route: path}
+	if path != "/" {
+		nr.segs = strings.Split(path[1:], "/")
+	}
+	for i, seg := range nr.segs {
+		if seg[0] != ':' {
+			continue
+		}
+		if _, pattern := getRegParam(seg); pattern != "" {
+			if nr.exprs == nil {
+				nr.exprs = make(map[int]*regexp.Regexp, 2)
+			}
+			nr.exprs[i] = compileRegParam(seg, pattern)
+		}
+	}
+	r.names[name] = nr
+}
+
+// urlFor 根据路由名字和参数生成 URL。
+// 参数路由和正则路由按照参数名取值，正则路由的值必须匹配正则；
+// 具名通配符同样按照名字取值，只有位于末尾的通配符的值可以包含 /；匿名通配符 * 无法生成 URL
+func (r *router) urlFor(name string, params map[string]string) (string, error) {
+	nr, ok := r.names[name]
+	if !ok {
+		return "", fmt.Errorf("web: 路由 %s 不存在", name)
+	}
+	if len(nr.segs) == 0 {
+		return "/", nil
+	}
+	var sb strings.Builder
+	for i, seg := range nr.segs {
+		sb.WriteByte('/')
+		switch seg[0] {
+		case ':':
+			key, pattern := getRegParam(seg)
+			if pattern == "" {
+				key = seg[1:]
+			}
+			val, ok := params[key]
+			if !ok || val == "" {
+				return "", fmt.Errorf("web: 生成路由 %s 的 URL 缺少参数 %s", name, key)
+			}
+			if expr, ok := nr.exprs[i]; ok && !expr.MatchString(val) {
+				return "", fmt.Errorf("web: 生成路由 %s 的 URL 失败，参数 %s 的值 %s 不匹配正则 %s", name, key, val, pattern)
+			}
+			if err := checkSegment(name, key, val); err != nil {
+				return "", err
+			}
+			sb.WriteString(url.PathEscape(val))
+		case '*':
+			key := seg[1:]
+			if key == "" {
+				return "", fmt.Errorf("web: 路由 %s 含有匿名通配符，无法生成 URL", name)
+			}
+			val, ok := params[key]
+			if !ok || val == "" {
+				return "", fmt.Errorf("web: 生成路由 %s 的 URL 缺少参数 %s", name, key)
+			}
+			if i != len(nr.segs)-1 {
+				if err := checkSegment(name, key, val); err != nil {
+					return "", err
+				}
+				sb.WriteString(url.PathEscape(val))
+				continue
+			}
+			// 末尾的通配符匹配多段，每一段单独转义
+			parts := strings.Split(strings.Trim(val, "/"), "/")
+			for j, part := range parts {
+				if j > 0 {
+					sb.WriteByte('/')
+				}
+				sb.WriteString(url.PathEscape(part))
+			}
+		default:
+			sb.WriteString(seg)
+		}
+	}
+	return sb.String(), nil
+}
+
+// checkSegment 只匹配一段的参数不能含有 /。
+// 路由匹配的是解码之后的 path，转义成 %2F 也会被拆成两段，生成的 URL 永远匹配不到这个路由
+func checkSegment(name string, key string, val string) error {
+	if strings.Contains(val, "/") {
+		return fmt.Errorf("web: 生成路由 %s 的 URL 失败，参数 %s 的值 %s 含有 /", name, key, val)
+	}
+	return nil
+}
+
+// URLFor 根据路由名字和参数生成 URL，例如：
+//
+//	server.AddNamedRoute("user", http.MethodGet, "/user/:id(\\d+)", handler)
+//	server.URLFor("user", map[string]string{"id": "123"}) // /user/123
+func (hs *HttpServer) URLFor(name string, params map[string]string) (string, error) {
+	return hs.router.urlFor(name, params)
+}
+
+// URLFor 根据路由名字和参数生成 URL，见 HttpServer.URLFor
+func (c *Context) URLFor(name string, params map[string]string) (string, error) {
+	return c.router.urlFor(name, params)
+}
+
+// Redirect 重定向到 url，status 必须是 3xx，例如 http.StatusFound、http.StatusSeeOther。
+// 和 RespStatusCode 一样，重定向在发送响应的时候才生效，middleware 依旧可以修改
+func (c *Context) Redirect(status int, url string) error {
+	if status < http.StatusMultipleChoices || status > http.StatusPermanentRedirect {
+		return fmt.Errorf("web: 重定向的状态码必须是 3xx [%d]", status)
+	}
+	c.Header().Set("Location", url)
+	c.RespStatusCode = status
+	c.RespData = nil
+	return nil
+}
+
+// RedirectTo 重定向到具名路由，URL 的生成规则见 HttpServer.URLFor
+func (c *Context) RedirectTo(status int, name string, params map[string]string) error {
+	u, err := c.URLFor(name, params)
+	if err != nil {
+		return err
+	}
+	return c.Redirect(status, u)
+}
+
+// TemplateFuncs 返回给模板使用的函数，需要在解析模板之前注册：
+//
+//	tpl := template.New("").Funcs(server.TemplateFuncs())
+//
+// 模板里面使用 {{ urlFor "user" "id" .ID }} 生成 URL，参数按照名字、值成对传入
+func (hs *HttpServer) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"urlFor": func(name string, kvs ...any) (string, error) {
+			if len(kvs)%2 != 0 {
+				return "", fmt.Errorf("web: urlFor %s 的参数必须成对出现", name)
+			}
+			params := make(map[string]string, len(kvs)/2)
+			for i := 0; i < len(kvs); i += 2 {
+				params[fmt.Sprint(kvs[i])] = fmt.Sprint(kvs[i+1])
+			}
+			return hs.URLFor(name, params)
+		},
+	}
+}
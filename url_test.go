@@ -0,0 +1,244 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHttpServer_URLFor(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	s := NewHttpServer()
+	s.AddNamedRoute("home", http.MethodGet, "/", mockHandler)
+	s.AddNamedRoute("user", http.MethodGet, "/user/:id", mockHandler)
+	s.AddNamedRoute("order", http.MethodGet, "/order/:id(\\d+)/detail", mockHandler)
+	s.AddNamedRoute("files", http.MethodGet, "/files/*filepath", mockHandler)
+	s.AddNamedRoute("profile", http.MethodGet, "/users/*name/profile", mockHandler)
+	s.AddNamedRoute("static", http.MethodGet, "/static/*", mockHandler)
+	s.Group("/api").AddNamedRoute("api_user", http.MethodPost, "/user/:id", mockHandler)
+
+	testCases := []struct {
+		name    string
+		route   string
+		params  map[string]string
+		wantURL string
+		wantErr string
+	}{
+		{
+			name:    "root",
+			route:   "home",
+			wantURL: "/",
+		},
+		{
+			name:    "param",
+			route:   "user",
+			params:  map[string]string{"id": "123"},
+			wantURL: "/user/123",
+		},
+		{
+			name:    "escape",
+			route:   "user",
+			params:  map[string]string{"id": "a b"},
+			wantURL: "/user/a%20b",
+		},
+		{
+			name:    "slash in param",
+			route:   "user",
+			params:  map[string]string{"id": "a/b"},
+			wantErr: "web: 生成路由 user 的 URL 失败，参数 id 的值 a/b 含有 /",
+		},
+		{
+			name:    "slash in single segment wildcard",
+			route:   "profile",
+			params:  map[string]string{"name": "a/b"},
+			wantErr: "web: 生成路由 profile 的 URL 失败，参数 name 的值 a/b 含有 /",
+		},
+		{
+			name:    "missing param",
+			route:   "user",
+			wantErr: "web: 生成路由 user 的 URL 缺少参数 id",
+		},
+		{
+			name:    "regex",
+			route:   "order",
+			params:  map[string]string{"id": "123"},
+			wantURL: "/order/123/detail",
+		},
+		{
+			name:    "regex mismatch",
+			route:   "order",
+			params:  map[string]string{"id": "abc"},
+			wantErr: "web: 生成路由 order 的 URL 失败，参数 id 的值 abc 不匹配正则 \\d+",
+		},
+		{
+			name:    "catch-all",
+			route:   "files",
+			params:  map[string]string{"filepath": "css/a b.css"},
+			wantURL: "/files/css/a%20b.css",
+		},
+		{
+			name:    "single segment wildcard",
+			route:   "profile",
+			params:  map[string]string{"name": "tom"},
+			wantURL: "/users/tom/profile",
+		},
+		{
+			name:    "anonymous wildcard",
+			route:   "static",
+			wantErr: "web: 路由 static 含有匿名通配符，无法生成 URL",
+		},
+		{
+			name:    "group",
+			route:   "api_user",
+			params:  map[string]string{"id": "1"},
+			wantURL: "/api/user/1",
+		},
+		{
+			name:    "unknown",
+			route:   "abc",
+			wantErr: "web: 路由 abc 不存在",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := s.URLFor(tc.route, tc.params)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantURL, u)
+		})
+	}
+
+	assert.PanicsWithValue(t, "web: 路由名字冲突[user]", func() {
+		s.AddNamedRoute("user", http.MethodGet, "/account/:id", mockHandler)
+	})
+}
+
+// TestHttpServer_URLForRoundTrip 生成的 URL 必须能够匹配回原来的路由，并且拿到原来的参数
+func TestHttpServer_URLForRoundTrip(t *testing.T) {
+	s := NewHttpServer()
+	var params map[string]string
+	handler := func(ctx *Context) {
+		params = ctx.PathParams
+		ctx.RespStatusCode = http.StatusOK
+	}
+	s.AddNamedRoute("user", http.MethodGet, "/user/:name", handler)
+	s.AddNamedRoute("order", http.MethodGet, "/order/:id(\\d+)/detail", handler)
+	s.AddNamedRoute("files", http.MethodGet, "/files/*filepath", handler)
+	s.AddNamedRoute("profile", http.MethodGet, "/users/*name/profile", handler)
+
+	testCases := []struct {
+		route  string
+		params map[string]string
+	}{
+		{route: "user", params: map[string]string{"name": "a b?c#d%e"}},
+		{route: "order", params: map[string]string{"id": "42"}},
+		{route: "files", params: map[string]string{"filepath": "css/a b/c%.css"}},
+		{route: "profile", params: map[string]string{"name": "中文"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.route, func(t *testing.T) {
+			u, err := s.URLFor(tc.route, tc.params)
+			require.NoError(t, err)
+			params = nil
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, u, nil))
+			assert.Equal(t, http.StatusOK, recorder.Code, u)
+			assert.Equal(t, tc.params, params, u)
+		})
+	}
+}
+
+func TestHttpServer_URLForTemplate(t *testing.T) {
+	engine := &GoTemplateEngine{}
+	s := NewHttpServer(TemplateEngineOption(engine))
+	tpl, err := template.New("page").Funcs(s.TemplateFuncs()).
+		Parse(`<a href="{{ urlFor "user" "id" .ID }}">user</a>`)
+	require.NoError(t, err)
+	engine.T = tpl
+
+	s.AddNamedRoute("user", http.MethodGet, "/user/:id", func(ctx *Context) {})
+	s.AddRoute(http.MethodGet, "/page", func(ctx *Context) {
+		u, err := ctx.URLFor("user", map[string]string{"id": "1"})
+		require.NoError(t, err)
+		ctx.Resp.Header().Set("Location", u)
+		_ = ctx.Render("page", map[string]any{"ID": 123})
+	})
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/page", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "/user/1", recorder.Header().Get("Location"))
+	assert.Equal(t, `<a href="/user/123">user</a>`, recorder.Body.String())
+}
+
+func TestContext_Redirect(t *testing.T) {
+	var locations []string
+	s := NewHttpServer(MiddlewaresOption([]Middleware{
+		func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				next(ctx)
+				locations = append(locations, ctx.Header().Get("Location"))
+			}
+		},
+	}))
+	s.AddNamedRoute("user", http.MethodGet, "/user/:id(\\d+)", func(ctx *Context) {})
+	s.AddRoute(http.MethodGet, "/old", func(ctx *Context) {
+		require.NoError(t, ctx.Redirect(http.StatusMovedPermanently, "https://example.com/new"))
+	})
+	s.AddRoute(http.MethodPost, "/user", func(ctx *Context) {
+		require.NoError(t, ctx.RedirectTo(http.StatusSeeOther, "user", map[string]string{"id": "123"}))
+	})
+	s.AddRoute(http.MethodGet, "/bad", func(ctx *Context) {
+		assert.EqualError(t, ctx.Redirect(http.StatusOK, "/"), "web: 重定向的状态码必须是 3xx [200]")
+		assert.EqualError(t, ctx.RedirectTo(http.StatusFound, "user", map[string]string{"id": "abc"}),
+			"web: 生成路由 user 的 URL 失败，参数 id 的值 abc 不匹配正则 \\d+")
+		ctx.RespStatusCode = http.StatusBadRequest
+	})
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "redirect",
+			method:       http.MethodGet,
+			path:         "/old",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "https://example.com/new",
+		},
+		{
+			name:         "redirect to named route",
+			method:       http.MethodPost,
+			path:         "/user",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/user/123",
+		},
+		{
+			name:     "invalid",
+			method:   http.MethodGet,
+			path:     "/bad",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			locations = nil
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"))
+			// middleware 能够看到重定向的地址
+			assert.Equal(t, []string{tc.wantLocation}, locations)
+		})
+	}
+}
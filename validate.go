@@ -0,0 +1,415 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError 一个字段没有通过校验，或者绑定的时候转换失败
+type FieldError struct {
+	// Field 字段名，优先使用 json tag，其次是 Bind 用到的 tag，最后是结构体字段名。
+	// 嵌套的字段用 . 连接，切片和 map 的元素用 [] 表示，例如 items[0].name
+	Field string `json:"field"`
+	// Rule 没有通过的规则，绑定时转换失败为 type
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError 所有没有通过校验的字段
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, f := range e.Errors {
+		msgs = append(msgs, f.Field+" "+f.Message)
+	}
+	return "web: 参数校验失败: " + strings.Join(msgs, "; ")
+}
+
+// Validate 按照 validate tag 校验结构体，Bind 和 BindJSON 成功之后会自动调用。支持的规则：
+//
+//	required   不能是零值，指针不能为 nil，字符串、切片、map 不能为空
+//	omitempty  零值的时候跳过后面的规则
+//	min=n      数字不能小于 n，字符串、切片、map 的长度不能小于 n
+//	max=n      数字不能大于 n，字符串、切片、map 的长度不能大于 n
+//	len=n      数字必须等于 n，字符串、切片、map 的长度必须等于 n
+//	oneof=a b  必须是列出来的值之一，用空格分隔
+//	email      必须是合法的邮箱地址
+//	regex=expr 字符串必须匹配正则，正则里面可能有逗号，所以 regex 必须是最后一条规则
+//	dive       后面的规则作用于切片或者 map 的每个元素
+//
+// 规则之间用逗号分隔，例如 `validate:"required,min=1,max=20"`。
+// 结构体类型的字段，以及 dive 之后的结构体元素，会递归校验。
+// 规则写错，或者规则不支持字段的类型（例如 int 字段上的 email）的时候会 panic。
+// 规则在第一次校验某个类型的时候才解析，想在启动阶段发现问题的话，注册路由的时候调用 CheckValidateRules
+func Validate(val any) error {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("web: Validate 的参数必须是结构体或者结构体指针")
+	}
+	var errs []FieldError
+	validateStruct(rv, "", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// CheckValidateRules 检查 val 的类型以及嵌套的结构体上所有的 validate tag，
+// 规则写错或者不支持字段类型的时候返回 error，而不是等到处理请求的时候才 panic。例如：
+//
+//	server.AddRoute(http.MethodPost, "/user", createUser)
+//	if err := web.CheckValidateRules(CreateUserReq{}); err != nil {
+//		panic(err)
+//	}
+func CheckValidateRules(val any) (err error) {
+	t := reflect.TypeOf(val)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("web: CheckValidateRules 的参数必须是结构体或者结构体指针")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			err = errors.New(msg)
+		}
+	}()
+	checkStructRules(t, make(map[reflect.Type]bool))
+	return nil
+}
+
+// checkStructRules 解析 t 和嵌套的结构体的规则，规则有问题的时候 validateFields 会 panic
+func checkStructRules(t reflect.Type, visited map[reflect.Type]bool) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	for _, f := range validateFields(t) {
+		ft := t.FieldByIndex(f.index).Type
+		for _, r := range f.rules {
+			if r.name == "dive" {
+				ft = derefType(ft).Elem()
+			}
+		}
+		if ft = derefType(ft); ft.Kind() == reflect.Struct && ft != timeType {
+			checkStructRules(ft, visited)
+		}
+	}
+}
+
+type validateRule struct {
+	name  string
+	param string
+	num   float64
+	expr  *regexp.Regexp
+	oneof []string
+}
+
+type validateField struct {
+	index []int
+	name  string
+	rules []validateRule
+}
+
+var (
+	// validateCache 结构体类型到字段规则的缓存，规则只需要解析一次
+	validateCache sync.Map
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+func validateStruct(v reflect.Value, prefix string, errs *[]FieldError) {
+	for _, f := range validateFields(v.Type()) {
+		validateValue(v.FieldByIndex(f.index), prefix+f.name, f.rules, errs)
+	}
+}
+
+func validateValue(v reflect.Value, name string, rules []validateRule, errs *[]FieldError) {
+	for i, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if v.IsZero() {
+				return
+			}
+			continue
+		case "required":
+			if isEmptyValue(v) {
+				*errs = append(*errs, FieldError{Field: name, Rule: r.name, Message: "不能为空"})
+				return
+			}
+			continue
+		case "dive":
+			dv := indirectValue(v)
+			switch dv.Kind() {
+			case reflect.Slice, reflect.Array:
+				for j := 0; j < dv.Len(); j++ {
+					validateValue(dv.Index(j), fmt.Sprintf("%s[%d]", name, j), rules[i+1:], errs)
+				}
+			case reflect.Map:
+				keys := dv.MapKeys()
+				sort.Slice(keys, func(a, b int) bool {
+					return fmt.Sprint(keys[a].Interface()) < fmt.Sprint(keys[b].Interface())
+				})
+				for _, key := range keys {
+					validateValue(dv.MapIndex(key), fmt.Sprintf("%s[%v]", name, key.Interface()), rules[i+1:], errs)
+				}
+			}
+			return
+		}
+		dv := indirectValue(v)
+		// nil 指针只检查 required
+		if !dv.IsValid() {
+			return
+		}
+		if msg, ok := r.check(dv); !ok {
+			*errs = append(*errs, FieldError{Field: name, Rule: r.name, Param: r.param, Message: msg})
+			return
+		}
+	}
+	if dv := indirectValue(v); dv.Kind() == reflect.Struct && dv.Type() != timeType {
+		validateStruct(dv, name+".", errs)
+	}
+}
+
+func (r validateRule) check(v reflect.Value) (string, bool) {
+	switch r.name {
+	case "min", "max", "len":
+		n, isLen := numberOrLen(v, r.name)
+		var ok bool
+		switch r.name {
+		case "min":
+			ok = n >= r.num
+		case "max":
+			ok = n <= r.num
+		default:
+			ok = n == r.num
+		}
+		if ok {
+			return "", true
+		}
+		prefix := ""
+		if isLen {
+			prefix = "长度"
+		}
+		switch r.name {
+		case "min":
+			return fmt.Sprintf("%s不能小于 %s", prefix, r.param), false
+		case "max":
+			return fmt.Sprintf("%s不能大于 %s", prefix, r.param), false
+		default:
+			return fmt.Sprintf("%s必须等于 %s", prefix, r.param), false
+		}
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, o := range r.oneof {
+			if s == o {
+				return "", true
+			}
+		}
+		return fmt.Sprintf("必须是 [%s] 其中之一", strings.Join(r.oneof, " ")), false
+	case "email":
+		s := mustString(v, r.name)
+		if addr, err := mail.ParseAddress(s); err == nil && addr.Address == s {
+			return "", true
+		}
+		return "不是合法的邮箱地址", false
+	case "regex":
+		if r.expr.MatchString(mustString(v, r.name)) {
+			return "", true
+		}
+		return fmt.Sprintf("必须匹配 %s", r.param), false
+	}
+	return "", true
+}
+
+// numberOrLen 返回数字本身，或者字符串、切片、map 的长度
+func numberOrLen(v reflect.Value, rule string) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+	panic(fmt.Sprintf("web: 校验规则 %s 不支持类型 %s", rule, v.Type()))
+}
+
+func mustString(v reflect.Value, rule string) string {
+	if v.Kind() != reflect.String {
+		panic(fmt.Sprintf("web: 校验规则 %s 不支持类型 %s", rule, v.Type()))
+	}
+	return v.String()
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func validateFields(t reflect.Type) []validateField {
+	if fields, ok := validateCache.Load(t); ok {
+		return fields.([]validateField)
+	}
+	var fields []validateField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// 匿名嵌入的结构体，字段当成外层结构体的字段
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for _, ef := range validateFields(f.Type) {
+				ef.index = append([]int{i}, ef.index...)
+				fields = append(fields, ef)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		rules := parseValidateTag(f.Tag.Get("validate"))
+		checkRuleTypes(t.Name()+"."+f.Name, f.Type, rules)
+		ft := derefType(f.Type)
+		// 没有规则，也不是需要递归校验的结构体
+		if len(rules) == 0 && (ft.Kind() != reflect.Struct || ft == timeType) {
+			continue
+		}
+		fields = append(fields, validateField{index: []int{i}, name: fieldName(f), rules: rules})
+	}
+	validateCache.Store(t, fields)
+	return fields
+}
+
+// checkRuleTypes 检查规则是否支持字段的类型，不支持的时候 panic。
+// interface 类型的字段要到校验的时候才知道实际类型，不检查
+func checkRuleTypes(field string, ft reflect.Type, rules []validateRule) {
+	for _, r := range rules {
+		ft = derefType(ft)
+		if ft.Kind() == reflect.Interface {
+			return
+		}
+		ok := true
+		switch r.name {
+		case "dive":
+			switch ft.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				ft = ft.Elem()
+			default:
+				ok = false
+			}
+		case "min", "max", "len":
+			switch ft.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64,
+				reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			default:
+				ok = false
+			}
+		case "email", "regex":
+			ok = ft.Kind() == reflect.String
+		}
+		if !ok {
+			panic(fmt.Sprintf("web: 字段 %s 的校验规则 %s 不支持类型 %s", field, r.name, ft))
+		}
+	}
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func fieldName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	for _, source := range bindSources {
+		if name := f.Tag.Get(source); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func parseValidateTag(tag string) []validateRule {
+	if tag == "" {
+		return nil
+	}
+	var rules []validateRule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(part, "=")
+		r := validateRule{name: name, param: param}
+		switch name {
+		case "required", "omitempty", "dive", "email":
+		case "min", "max", "len":
+			num, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				panic(fmt.Sprintf("web: 非法的校验规则 [%s]", part))
+			}
+			r.num = num
+		case "oneof":
+			r.oneof = strings.Fields(param)
+			if len(r.oneof) == 0 {
+				panic(fmt.Sprintf("web: 非法的校验规则 [%s]", part))
+			}
+		case "regex":
+			expr, err := regexp.Compile(param)
+			if err != nil {
+				panic(fmt.Sprintf("web: 非法的校验规则 [%s]: %v", part, err))
+			}
+			r.expr = expr
+		default:
+			panic(fmt.Sprintf("web: 未知的校验规则 [%s]", part))
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
@@ -0,0 +1,237 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"len=6,regex=^[0-9]+$"`
+}
+
+type validateItem struct {
+	SKU   string `json:"sku" validate:"required"`
+	Count int    `json:"count" validate:"min=1,max=99"`
+}
+
+type validateOrderReq struct {
+	Name    string            `json:"name" validate:"required,max=5"`
+	Email   string            `json:"email" validate:"omitempty,email"`
+	Status  string            `json:"status" validate:"oneof=new paid"`
+	Level   int               `query:"level" validate:"oneof=1 2 3"`
+	Tags    []string          `json:"tags" validate:"max=2,dive,min=2"`
+	Items   []validateItem    `json:"items" validate:"required,dive"`
+	Address *validateAddress  `json:"address"`
+	Labels  map[string]string `json:"labels" validate:"dive,regex=^[a-z,]+$"`
+	Note    *string           `json:"note" validate:"min=2"`
+}
+
+func TestValidate(t *testing.T) {
+	note := "a"
+	testCases := []struct {
+		name    string
+		val     any
+		wantErr []FieldError
+	}{
+		{
+			name: "valid",
+			val: &validateOrderReq{
+				Name: "Tom", Email: "tom@example.com", Status: "new", Level: 1,
+				Tags:    []string{"ab"},
+				Items:   []validateItem{{SKU: "a", Count: 1}},
+				Address: &validateAddress{City: "SZ", Zip: "518000"},
+				Labels:  map[string]string{"k": "a,b"},
+			},
+		},
+		{
+			name: "invalid",
+			val: validateOrderReq{
+				Name: "Jerry Tom", Email: "tom", Status: "closed", Level: 4,
+				Tags:    []string{"a", "bc", "d"},
+				Items:   []validateItem{{SKU: "a", Count: 1}, {Count: 100}},
+				Address: &validateAddress{Zip: "51800a"},
+				Labels:  map[string]string{"b": "B", "a": "ok"},
+				Note:    &note,
+			},
+			wantErr: []FieldError{
+				{Field: "name", Rule: "max", Param: "5", Message: "长度不能大于 5"},
+				{Field: "email", Rule: "email", Message: "不是合法的邮箱地址"},
+				{Field: "status", Rule: "oneof", Param: "new paid", Message: "必须是 [new paid] 其中之一"},
+				{Field: "level", Rule: "oneof", Param: "1 2 3", Message: "必须是 [1 2 3] 其中之一"},
+				{Field: "tags", Rule: "max", Param: "2", Message: "长度不能大于 2"},
+				{Field: "items[1].sku", Rule: "required", Message: "不能为空"},
+				{Field: "items[1].count", Rule: "max", Param: "99", Message: "不能大于 99"},
+				{Field: "address.city", Rule: "required", Message: "不能为空"},
+				{Field: "address.zip", Rule: "regex", Param: "^[0-9]+$", Message: "必须匹配 ^[0-9]+$"},
+				{Field: "labels[b]", Rule: "regex", Param: "^[a-z,]+$", Message: "必须匹配 ^[a-z,]+$"},
+				{Field: "note", Rule: "min", Param: "2", Message: "长度不能小于 2"},
+			},
+		},
+		{
+			name: "dive",
+			val: &validateOrderReq{
+				Name: "Tom", Status: "paid", Level: 2,
+				Tags: []string{"a"},
+			},
+			wantErr: []FieldError{
+				{Field: "tags[0]", Rule: "min", Param: "2", Message: "长度不能小于 2"},
+				{Field: "items", Rule: "required", Message: "不能为空"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.val)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var validateErr *ValidationError
+			require.ErrorAs(t, err, &validateErr)
+			assert.Equal(t, tc.wantErr, validateErr.Errors)
+		})
+	}
+}
+
+func TestValidate_invalidRule(t *testing.T) {
+	type unknownRule struct {
+		Name string `validate:"unknown"`
+	}
+	assert.PanicsWithValue(t, "web: 未知的校验规则 [unknown]", func() {
+		_ = Validate(unknownRule{})
+	})
+	type badMin struct {
+		Name string `validate:"min=a"`
+	}
+	assert.PanicsWithValue(t, "web: 非法的校验规则 [min=a]", func() {
+		_ = Validate(badMin{})
+	})
+	// 规则不支持字段的类型，解析规则的时候就 panic，不管字段的值是什么
+	type emailOnInt struct {
+		ID int `validate:"email"`
+	}
+	assert.PanicsWithValue(t, "web: 字段 emailOnInt.ID 的校验规则 email 不支持类型 int", func() {
+		_ = Validate(emailOnInt{})
+	})
+	type minOnBool struct {
+		OK *bool `validate:"omitempty,min=1"`
+	}
+	assert.PanicsWithValue(t, "web: 字段 minOnBool.OK 的校验规则 min 不支持类型 bool", func() {
+		_ = Validate(minOnBool{})
+	})
+	type diveOnString struct {
+		Name string `validate:"dive,required"`
+	}
+	assert.PanicsWithValue(t, "web: 字段 diveOnString.Name 的校验规则 dive 不支持类型 string", func() {
+		_ = Validate(diveOnString{})
+	})
+}
+
+func TestCheckValidateRules(t *testing.T) {
+	type item struct {
+		Price float64 `validate:"regex=^\\d+$"`
+	}
+	type order struct {
+		Items []item `validate:"required,dive"`
+	}
+	assert.EqualError(t, CheckValidateRules(&order{}), "web: 字段 item.Price 的校验规则 regex 不支持类型 float64")
+
+	type goodItem struct {
+		Name string `validate:"required,max=20"`
+	}
+	type goodOrder struct {
+		Items map[string]*goodItem `validate:"dive"`
+		Tags  []string             `validate:"max=3,dive,min=1"`
+		Any   any                  `validate:"email"`
+	}
+	assert.NoError(t, CheckValidateRules(goodOrder{}))
+	assert.EqualError(t, CheckValidateRules(1), "web: CheckValidateRules 的参数必须是结构体或者结构体指针")
+}
+
+func TestContext_BindOrReject(t *testing.T) {
+	type createUserReq struct {
+		Name string `json:"name" validate:"required"`
+		Age  int    `json:"age" query:"age" validate:"min=18"`
+	}
+	s := NewHttpServer()
+	s.AddRoute(http.MethodPost, "/user", func(ctx *Context) {
+		var req createUserReq
+		if !ctx.BindOrReject(&req) {
+			return
+		}
+		ctx.RespStatusCode = http.StatusCreated
+	})
+
+	testCases := []struct {
+		name     string
+		query    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "ok",
+			body:     `{"name":"Tom","age":18}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "validation",
+			body:     `{"age":17}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"请求参数校验失败",
+"errors":[{"field":"name","rule":"required","message":"不能为空"},
+{"field":"age","rule":"min","param":"18","message":"不能小于 18"}]}`,
+		},
+		{
+			name:     "bind",
+			query:    "age=abc",
+			body:     `{"name":"Tom"}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"请求参数格式错误",
+"errors":[{"field":"age","rule":"type","message":"值 \"abc\" 不合法: invalid syntax"}]}`,
+		},
+		{
+			name:     "body",
+			body:     `{`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"web: 解析请求体失败: unexpected EOF"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user?"+tc.query, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+				assert.JSONEq(t, tc.wantBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestBindErrorHandlerOption(t *testing.T) {
+	s := NewHttpServer(BindErrorHandlerOption(func(ctx *Context, err error) {
+		ctx.RespStatusCode = http.StatusUnprocessableEntity
+		ctx.RespData = []byte(err.Error())
+	}))
+	s.AddRoute(http.MethodPost, "/", func(ctx *Context) {
+		var req struct {
+			Name string `json:"name" validate:"required"`
+		}
+		ctx.BindOrReject(&req)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, "web: 参数校验失败: name 不能为空", recorder.Body.String())
+}
@@ -0,0 +1,168 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrValueMissing 参数不存在，可以通过 errors.Is 判断
+var ErrValueMissing = errors.New("web: 参数不存在")
+
+// ValueError 读取或者转换参数失败，带上了参数的来源和名字，方便直接返回给客户端
+type ValueError struct {
+	// Source 参数的来源：query、form、header 或者 path
+	Source string
+	Key    string
+	Value  string
+	Err    error
+}
+
+func (e *ValueError) Error() string {
+	if errors.Is(e.Err, ErrValueMissing) {
+		return fmt.Sprintf("web: %s 参数 %s 不存在", e.Source, e.Key)
+	}
+	return fmt.Sprintf("web: %s 参数 %s 的值 %q 不合法: %v", e.Source, e.Key, e.Value, e.Err)
+}
+
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// StringValue 请求参数的值，提供转换成各种类型的方法。
+// 参数不存在的时候，所有的转换方法都会返回 ErrValueMissing，可以先用 Default 设置默认值
+type StringValue struct {
+	source string
+	key    string
+	vals   []string
+	err    error
+}
+
+func newStringValue(source string, key string, vals []string) *StringValue {
+	s := &StringValue{
+		source: source,
+		key:    key,
+		vals:   vals,
+	}
+	if len(vals) == 0 {
+		s.err = &ValueError{Source: source, Key: key, Err: ErrValueMissing}
+	}
+	return s
+}
+
+// Default 参数不存在的时候使用 val，参数存在但是不合法的时候依旧会返回 error
+func (s *StringValue) Default(val string) *StringValue {
+	if !errors.Is(s.err, ErrValueMissing) {
+		return s
+	}
+	return &StringValue{
+		source: s.source,
+		key:    s.key,
+		vals:   []string{val},
+	}
+}
+
+// String 返回第一个值
+func (s *StringValue) String() (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.vals[0], nil
+}
+
+// Strings 返回所有的值，例如 ?id=1&id=2 返回 ["1", "2"]
+func (s *StringValue) Strings() ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.vals, nil
+}
+
+func (s *StringValue) AsInt64() (int64, error) {
+	return convert(s, func(val string) (int64, error) {
+		return strconv.ParseInt(val, 10, 64)
+	})
+}
+
+func (s *StringValue) AsInt() (int, error) {
+	return convert(s, strconv.Atoi)
+}
+
+func (s *StringValue) AsUint64() (uint64, error) {
+	return convert(s, func(val string) (uint64, error) {
+		return strconv.ParseUint(val, 10, 64)
+	})
+}
+
+func (s *StringValue) AsFloat64() (float64, error) {
+	return convert(s, func(val string) (float64, error) {
+		return strconv.ParseFloat(val, 64)
+	})
+}
+
+// AsBool 支持 1、t、true、0、f、false 等写法，见 strconv.ParseBool
+func (s *StringValue) AsBool() (bool, error) {
+	return convert(s, strconv.ParseBool)
+}
+
+// AsDuration 支持 300ms、1h30m 这样的写法，见 time.ParseDuration
+func (s *StringValue) AsDuration() (time.Duration, error) {
+	return convert(s, time.ParseDuration)
+}
+
+// AsTime 按照 layout 解析时间，例如 time.RFC3339、"2006-01-02"
+func (s *StringValue) AsTime(layout string) (time.Time, error) {
+	return convert(s, func(val string) (time.Time, error) {
+		return time.Parse(layout, val)
+	})
+}
+
+// AsInt64Slice 把所有的值转换成整数，每个值也可以用逗号分隔，
+// 例如 ?id=1&id=2,3 返回 [1, 2, 3]
+func (s *StringValue) AsInt64Slice() ([]int64, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	var res []int64
+	for _, val := range s.vals {
+		for _, part := range strings.Split(val, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return nil, s.wrap(part, err)
+			}
+			res = append(res, n)
+		}
+	}
+	return res, nil
+}
+
+func convert[T any](s *StringValue, fn func(val string) (T, error)) (T, error) {
+	var t T
+	if s.err != nil {
+		return t, s.err
+	}
+	res, err := fn(s.vals[0])
+	if err != nil {
+		return t, s.wrap(s.vals[0], err)
+	}
+	return res, nil
+}
+
+func (s *StringValue) wrap(val string, err error) error {
+	return &ValueError{Source: s.source, Key: s.key, Value: val, Err: unwrapNumError(err)}
+}
+
+// unwrapNumError strconv 的错误信息里面已经带上了值，ValueError 里面也有，去掉重复的部分
+func unwrapNumError(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_QueryValue(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet,
+		"/?id=1&id=2,3&name=tom&active=true&ratio=0.5&timeout=1m30s&day=2023-01-02&age=-1&empty=", nil)
+	ctx := &Context{Req: req}
+
+	name, err := ctx.QueryValue("name").String()
+	require.NoError(t, err)
+	assert.Equal(t, "tom", name)
+
+	// 同名参数取第一个值
+	id, err := ctx.QueryValue("id").AsInt64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	ids, err := ctx.QueryValue("id").Strings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2,3"}, ids)
+	int64s, err := ctx.QueryValue("id").AsInt64Slice()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, int64s)
+
+	active, err := ctx.QueryValue("active").AsBool()
+	require.NoError(t, err)
+	assert.True(t, active)
+	ratio, err := ctx.QueryValue("ratio").AsFloat64()
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, ratio)
+	timeout, err := ctx.QueryValue("timeout").AsDuration()
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+	day, err := ctx.QueryValue("day").AsTime("2006-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), day)
+	empty, err := ctx.QueryValue("empty").String()
+	require.NoError(t, err)
+	assert.Equal(t, "", empty)
+
+	// 参数不存在的时候使用默认值，存在但是不合法的时候依旧报错
+	page, err := ctx.QueryValue("page").Default("10").AsInt()
+	require.NoError(t, err)
+	assert.Equal(t, 10, page)
+	_, err = ctx.QueryValue("name").Default("10").AsInt()
+	assert.EqualError(t, err, `web: query 参数 name 的值 "tom" 不合法: invalid syntax`)
+
+	_, err = ctx.QueryValue("page").AsInt()
+	assert.EqualError(t, err, "web: query 参数 page 不存在")
+	assert.ErrorIs(t, err, ErrValueMissing)
+	_, err = ctx.QueryValue("age").AsUint64()
+	var valErr *ValueError
+	require.ErrorAs(t, err, &valErr)
+	assert.Equal(t, &ValueError{Source: "query", Key: "age", Value: "-1", Err: valErr.Err}, valErr)
+	_, err = ctx.QueryValue("ratio").AsInt64Slice()
+	assert.EqualError(t, err, `web: query 参数 ratio 的值 "0.5" 不合法: invalid syntax`)
+	_, err = ctx.QueryValue("day").AsTime(time.RFC3339)
+	assert.ErrorAs(t, err, &valErr)
+	assert.Equal(t, "day", valErr.Key)
+}
+
+func TestContext_FormValue(t *testing.T) {
+	form := url.Values{"age": []string{"18"}}
+	req := httptest.NewRequest(http.MethodPost, "/?from=query", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := &Context{Req: req}
+
+	age, err := ctx.FormValue("age").AsInt()
+	require.NoError(t, err)
+	assert.Equal(t, 18, age)
+	from, err := ctx.FormValue("from").String()
+	require.NoError(t, err)
+	assert.Equal(t, "query", from)
+	_, err = ctx.FormValue("name").String()
+	assert.EqualError(t, err, "web: form 参数 name 不存在")
+}
+
+func TestContext_PathValue(t *testing.T) {
+	ctx := &Context{PathParams: map[string]string{"id": "12"}}
+	id, err := ctx.PathValue("id").AsUint64()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), id)
+	_, err = ctx.PathValue("name").String()
+	assert.EqualError(t, err, "web: path 参数 name 不存在")
+}
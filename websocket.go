@@ -0,0 +1,494 @@
+package web
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket 消息类型，和 RFC 6455 的 opcode 一致
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// WebSocket 关闭码，见 RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+// defaultMaxMessageSize 默认的单条消息最大字节数
+const defaultMaxMessageSize = 1 << 20
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// CloseError 对端发送了关闭帧，或者因为协议错误关闭了连接
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("web: WebSocket 连接已关闭 [%d] %s", e.Code, e.Text)
+}
+
+// WebSocketOption 配置 WebSocket 握手和连接
+type WebSocketOption func(u *websocketUpgrader)
+
+type websocketUpgrader struct {
+	maxMessageSize int64
+	subprotocols   []string
+	checkOrigin    func(r *http.Request) bool
+}
+
+// WebSocketMaxMessageSize 设置单条消息的最大字节数，默认为 1MB。
+// 超过之后以 CloseMessageTooBig 关闭连接。帧的长度由对端决定，所以必须有上限，size 必须大于 0
+func WebSocketMaxMessageSize(size int64) WebSocketOption {
+	if size <= 0 {
+		panic(fmt.Sprintf("web: WebSocket 消息的大小限制必须大于 0 [%d]", size))
+	}
+	return func(u *websocketUpgrader) {
+		u.maxMessageSize = size
+	}
+}
+
+// WebSocketSubprotocols 设置服务端支持的子协议，按照优先级排列
+func WebSocketSubprotocols(protocols ...string) WebSocketOption {
+	return func(u *websocketUpgrader) {
+		u.subprotocols = protocols
+	}
+}
+
+// WebSocketCheckOrigin 设置校验 Origin 的逻辑，返回 false 的时候拒绝握手。
+// 默认只允许没有 Origin 或者 Origin 和 Host 一致的请求，防止跨站 WebSocket 劫持
+func WebSocketCheckOrigin(fn func(r *http.Request) bool) WebSocketOption {
+	return func(u *websocketUpgrader) {
+		u.checkOrigin = fn
+	}
+}
+
+// UpgradeWebSocket 把当前请求升级成 WebSocket 连接。
+// 路由和 middleware 的处理和普通请求一样，所以鉴权之类的逻辑在升级之前就已经执行过了。
+// 握手失败的时候会设置好 RespStatusCode 并返回 error，handler 直接返回就可以。
+// 升级之后连接就交给了 WebSocket，RespStatusCode 为 101，RespData 不再生效
+func (c *Context) UpgradeWebSocket(opts ...WebSocketOption) (*WebSocket, error) {
+	u := &websocketUpgrader{
+		maxMessageSize: defaultMaxMessageSize,
+		checkOrigin:    sameOrigin,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	req := c.Req
+	if req.Method != http.MethodGet {
+		return nil, c.websocketFail(http.StatusMethodNotAllowed, "web: WebSocket 握手必须使用 GET")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") ||
+		!headerContainsToken(req.Header, "Upgrade", "websocket") {
+		return nil, c.websocketFail(http.StatusBadRequest, "web: 不是 WebSocket 握手请求")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, c.websocketFail(http.StatusUpgradeRequired, "web: 不支持的 WebSocket 版本")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, c.websocketFail(http.StatusBadRequest, "web: 非法的 Sec-WebSocket-Key")
+	}
+	if !u.checkOrigin(req) {
+		return nil, c.websocketFail(http.StatusForbidden, "web: WebSocket 请求的 Origin 不被允许")
+	}
+	subprotocol := u.selectSubprotocol(req)
+
+	conn, brw, err := c.respWriter().Hijack()
+	if err != nil {
+		return nil, c.websocketFail(http.StatusInternalServerError, err.Error())
+	}
+	// http.Server 设置的超时对 WebSocket 没有意义，由使用者通过 SetReadDeadline 控制
+	_ = conn.SetDeadline(time.Time{})
+	var sb strings.Builder
+	sb.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	sb.WriteString("Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n")
+	if subprotocol != "" {
+		sb.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	sb.WriteString("\r\n")
+	if _, err = conn.Write([]byte(sb.String())); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	c.RespStatusCode = http.StatusSwitchingProtocols
+	ws := newWebSocket(conn, brw.Reader, false, u.maxMessageSize)
+	ws.subprotocol = subprotocol
+	return ws, nil
+}
+
+func (c *Context) websocketFail(status int, msg string) error {
+	c.RespStatusCode = status
+	c.RespData = []byte(http.StatusText(status))
+	return errors.New(msg)
+}
+
+func (u *websocketUpgrader) selectSubprotocol(req *http.Request) string {
+	var offered []string
+	for _, v := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			offered = append(offered, strings.TrimSpace(p))
+		}
+	}
+	for _, p := range u.subprotocols {
+		for _, o := range offered {
+			if p == o {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func websocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// WebSocket 一条 WebSocket 连接。
+// ReadMessage 只能在一个 goroutine 里面调用，写消息的方法可以并发调用
+type WebSocket struct {
+	conn net.Conn
+	br   *bufio.Reader
+	// client 为 true 的时候发送的帧需要掩码，接收的帧不能有掩码，测试里面用来模拟客户端
+	client         bool
+	maxMessageSize int64
+	subprotocol    string
+	pongHandler    func(data []byte)
+
+	writeMutex sync.Mutex
+	closeSent  bool
+}
+
+// newWebSocket maxMessageSize 不大于 0 的时候使用默认的 1MB
+func newWebSocket(conn net.Conn, br *bufio.Reader, client bool, maxMessageSize int64) *WebSocket {
+	if maxMessageSize <= 0 {
+		maxMessageSize = defaultMaxMessageSize
+	}
+	return &WebSocket{
+		conn:           conn,
+		br:             br,
+		client:         client,
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+// Subprotocol 握手时协商出来的子协议，没有的话为空字符串
+func (ws *WebSocket) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// RemoteAddr 对端的地址
+func (ws *WebSocket) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// SetReadDeadline 设置读超时，超时之后 ReadMessage 返回 error，连接不能再继续使用。
+// 一般配合 OnPong 使用：定时发送 Ping，收到 Pong 之后延长读超时
+func (ws *WebSocket) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 设置写超时
+func (ws *WebSocket) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// OnPong 设置收到 Pong 时的回调，回调在 ReadMessage 所在的 goroutine 里面执行。
+// 收到 Ping 的时候会自动回复 Pong，不需要处理
+func (ws *WebSocket) OnPong(fn func(data []byte)) {
+	ws.pongHandler = fn
+}
+
+// ReadMessage 读取一条完整的消息，分片的消息会被拼接起来，控制帧在内部处理。
+// 对端关闭连接的时候返回 *CloseError
+func (ws *WebSocket) ReadMessage() (int, []byte, error) {
+	var (
+		msgType int
+		msg     []byte
+	)
+	for {
+		fin, opcode, payload, err := ws.readFrame(int64(len(msg)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err = ws.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if ws.pongHandler != nil {
+				ws.pongHandler(payload)
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, ws.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			if msgType != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "上一条分片消息还没有结束")
+			}
+			msgType = opcode
+		case 0:
+			if msgType == 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "没有需要继续的分片消息")
+			}
+		default:
+			return 0, nil, ws.fail(CloseProtocolError, fmt.Sprintf("未知的 opcode %d", opcode))
+		}
+		msg = append(msg, payload...)
+		if !fin {
+			continue
+		}
+		if msgType == TextMessage && !utf8.Valid(msg) {
+			return 0, nil, ws.fail(CloseInvalidFramePayloadData, "文本消息不是合法的 UTF-8")
+		}
+		return msgType, msg, nil
+	}
+}
+
+// WriteMessage 发送一条消息，msgType 为 TextMessage 或者 BinaryMessage
+func (ws *WebSocket) WriteMessage(msgType int, data []byte) error {
+	if msgType != TextMessage && msgType != BinaryMessage {
+		return fmt.Errorf("web: 非法的 WebSocket 消息类型 %d", msgType)
+	}
+	return ws.writeFrame(msgType, data)
+}
+
+// Ping 发送 Ping，对端回复的 Pong 会交给 OnPong 设置的回调
+func (ws *WebSocket) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("web: 控制帧的内容不能超过 125 字节")
+	}
+	return ws.writeFrame(PingMessage, data)
+}
+
+// Close 发送关闭帧并且关闭底层连接。已经收到对端关闭帧的时候，回复已经在 ReadMessage 里面发过了
+func (ws *WebSocket) Close(code int, text string) error {
+	_ = ws.writeClose(code, text)
+	return ws.conn.Close()
+}
+
+func (ws *WebSocket) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return ws.fail(CloseProtocolError, "关闭帧的内容不完整")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return ws.fail(CloseProtocolError, fmt.Sprintf("非法的关闭码 %d", closeErr.Code))
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return ws.fail(CloseInvalidFramePayloadData, "关闭原因不是合法的 UTF-8")
+		}
+	}
+	// 按照协议回复同样的关闭码
+	if closeErr.Code == CloseNoStatusReceived {
+		_ = ws.writeCloseFrame(nil)
+	} else {
+		_ = ws.writeClose(closeErr.Code, "")
+	}
+	return closeErr
+}
+
+// fail 因为协议错误关闭连接，返回对应的 CloseError
+func (ws *WebSocket) fail(code int, text string) error {
+	_ = ws.writeClose(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+func (ws *WebSocket) writeClose(code int, text string) error {
+	// 关闭原因最多 123 字节，加上关闭码刚好是控制帧的上限
+	if len(text) > 123 {
+		text = text[:123]
+	}
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return ws.writeCloseFrame(append(payload, text...))
+}
+
+func (ws *WebSocket) writeCloseFrame(payload []byte) error {
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
+	if ws.closeSent {
+		return nil
+	}
+	ws.closeSent = true
+	return ws.writeFrameLocked(CloseMessage, payload)
+}
+
+func validCloseCode(code int) bool {
+	switch code {
+	case 1004, CloseNoStatusReceived, CloseAbnormalClosure, 1015:
+		return false
+	}
+	return code >= 1000 && code <= 1014 || code >= 3000 && code <= 4999
+}
+
+// readFrame 读取一帧。received 是当前消息已经收到的字节数，用于检查消息大小
+func (ws *WebSocket) readFrame(received int64) (bool, int, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	opcode := int(head[0] & 0x0f)
+	masked := head[1]&0x80 != 0
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, ws.fail(CloseProtocolError, "不支持 WebSocket 扩展")
+	}
+	// 客户端发出的帧必须带掩码，服务端发出的帧不能带掩码
+	if masked == ws.client {
+		return false, 0, nil, ws.fail(CloseProtocolError, "帧的掩码不符合协议")
+	}
+
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		l := binary.BigEndian.Uint64(ext[:])
+		if l>>63 != 0 {
+			return false, 0, nil, ws.fail(CloseProtocolError, "帧的长度非法")
+		}
+		length = int64(l)
+	}
+
+	if opcode >= CloseMessage {
+		if !fin || length > 125 {
+			return false, 0, nil, ws.fail(CloseProtocolError, "控制帧不能分片，并且不能超过 125 字节")
+		}
+	} else if received+length > ws.maxMessageSize {
+		// 分配内存之前检查，避免对端用一个帧头就让服务端分配巨大的内存
+		return false, 0, nil, ws.fail(CloseMessageTooBig, "消息超过了大小限制")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, opcode, payload, nil
+}
+
+func (ws *WebSocket) writeFrame(opcode int, payload []byte) error {
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
+	if ws.closeSent {
+		return errors.New("web: WebSocket 连接已经关闭")
+	}
+	return ws.writeFrameLocked(opcode, payload)
+}
+
+func (ws *WebSocket) writeFrameLocked(opcode int, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|byte(opcode))
+	var maskBit byte
+	if ws.client {
+		maskBit = 0x80
+	}
+	switch l := len(payload); {
+	case l <= 125:
+		frame = append(frame, maskBit|byte(l))
+	case l <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(l))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(l))
+	}
+	if !ws.client {
+		frame = append(frame, payload...)
+		_, err := ws.conn.Write(frame)
+		return err
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+	start := len(frame)
+	frame = append(frame, payload...)
+	maskBytes(mask, frame[start:])
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+func maskBytes(mask [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= mask[i%4]
+	}
+}
@@ -0,0 +1,258 @@
+package web
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialWebSocket 在测试里面充当客户端，完成握手之后返回客户端一侧的连接
+func dialWebSocket(t *testing.T, addr string, path string, header http.Header) (*WebSocket, *http.Response) {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	require.NoError(t, req.Write(conn))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	require.NoError(t, err)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		_ = conn.Close()
+		return nil, resp
+	}
+	return newWebSocket(conn, br, true, 0), resp
+}
+
+// writeRawFrame 直接写一帧，用来构造分片消息和非法的帧
+func writeRawFrame(t *testing.T, ws *WebSocket, fin bool, opcode int, masked bool, payload []byte) {
+	var b0 byte = byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0, byte(len(payload))}
+	if masked {
+		frame[1] |= 0x80
+		mask := [4]byte{1, 2, 3, 4}
+		frame = append(frame, mask[:]...)
+		data := append([]byte{}, payload...)
+		maskBytes(mask, data)
+		payload = data
+	}
+	_, err := ws.conn.Write(append(frame, payload...))
+	require.NoError(t, err)
+}
+
+func TestContext_UpgradeWebSocket(t *testing.T) {
+	var (
+		routes   = make(chan string, 10)
+		serverWS = make(chan error, 1)
+	)
+	s := NewHttpServer(MiddlewaresOption([]Middleware{
+		func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				if ctx.Req.Header.Get("Authorization") != "token" {
+					ctx.RespStatusCode = http.StatusUnauthorized
+					return
+				}
+				next(ctx)
+				routes <- ctx.MatchedRoute
+			}
+		},
+	}))
+	s.AddRoute(http.MethodGet, "/chat/:room", func(ctx *Context) {
+		ws, err := ctx.UpgradeWebSocket(WebSocketSubprotocols("chat.v2", "chat.v1"), WebSocketMaxMessageSize(64))
+		if err != nil {
+			return
+		}
+		defer ws.Close(CloseNormalClosure, "")
+		for {
+			msgType, data, err := ws.ReadMessage()
+			if err != nil {
+				serverWS <- err
+				return
+			}
+			reply := ctx.PathParams["room"] + ":" + string(data)
+			if err = ws.WriteMessage(msgType, []byte(reply)); err != nil {
+				serverWS <- err
+				return
+			}
+		}
+	})
+	server := httptest.NewServer(s)
+	defer server.Close()
+	addr := server.Listener.Addr().String()
+	header := http.Header{
+		"Authorization":          []string{"token"},
+		"Sec-Websocket-Protocol": []string{"chat.v1, chat.v2"},
+	}
+
+	t.Run("echo", func(t *testing.T) {
+		client, resp := dialWebSocket(t, addr, "/chat/go", header)
+		require.NotNil(t, client)
+		assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+		assert.Equal(t, "chat.v2", resp.Header.Get("Sec-WebSocket-Protocol"))
+
+		require.NoError(t, client.WriteMessage(TextMessage, []byte("hello")))
+		msgType, data, err := client.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, TextMessage, msgType)
+		assert.Equal(t, "go:hello", string(data))
+
+		// 分片消息中间插入 Ping，服务端自动回复 Pong
+		var pongs []string
+		client.OnPong(func(data []byte) {
+			pongs = append(pongs, string(data))
+		})
+		writeRawFrame(t, client, false, BinaryMessage, true, []byte("wor"))
+		writeRawFrame(t, client, true, PingMessage, true, []byte("ping"))
+		writeRawFrame(t, client, true, 0, true, []byte("ld"))
+		msgType, data, err = client.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, BinaryMessage, msgType)
+		assert.Equal(t, "go:world", string(data))
+		assert.Equal(t, []string{"ping"}, pongs)
+
+		require.NoError(t, client.Close(CloseGoingAway, "bye"))
+		assert.Equal(t, &CloseError{Code: CloseGoingAway, Text: "bye"}, <-serverWS)
+		// 升级之前 middleware 已经执行过了
+		assert.Equal(t, "/chat/:room", <-routes)
+	})
+
+	t.Run("message too big", func(t *testing.T) {
+		client, _ := dialWebSocket(t, addr, "/chat/go", header)
+		require.NotNil(t, client)
+		defer client.Close(CloseNormalClosure, "")
+		require.NoError(t, client.WriteMessage(TextMessage, []byte(strings.Repeat("a", 65))))
+		_, _, err := client.ReadMessage()
+		assert.Equal(t, CloseMessageTooBig, err.(*CloseError).Code)
+		assert.Equal(t, CloseMessageTooBig, (<-serverWS).(*CloseError).Code)
+	})
+
+	t.Run("unmasked frame", func(t *testing.T) {
+		client, _ := dialWebSocket(t, addr, "/chat/go", header)
+		require.NotNil(t, client)
+		defer client.Close(CloseNormalClosure, "")
+		writeRawFrame(t, client, true, TextMessage, false, []byte("hello"))
+		_, _, err := client.ReadMessage()
+		assert.Equal(t, CloseProtocolError, err.(*CloseError).Code)
+		assert.Equal(t, CloseProtocolError, (<-serverWS).(*CloseError).Code)
+	})
+
+	t.Run("read deadline", func(t *testing.T) {
+		client, _ := dialWebSocket(t, addr, "/chat/go", header)
+		require.NotNil(t, client)
+		defer client.Close(CloseNormalClosure, "")
+		require.NoError(t, client.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+		_, _, err := client.ReadMessage()
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+	})
+
+	testCases := []struct {
+		name     string
+		header   http.Header
+		wantCode int
+	}{
+		{
+			name:     "unauthorized",
+			header:   http.Header{},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "cross origin",
+			header: http.Header{
+				"Authorization": []string{"token"},
+				"Origin":        []string{"http://evil.com"},
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "unsupported version",
+			header: http.Header{
+				"Authorization":         []string{"token"},
+				"Sec-Websocket-Version": []string{"8"},
+			},
+			wantCode: http.StatusUpgradeRequired,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, resp := dialWebSocket(t, addr, "/chat/go", tc.header)
+			assert.Nil(t, client)
+			assert.Equal(t, tc.wantCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestContext_UpgradeWebSocketNotWebSocket(t *testing.T) {
+	s := NewHttpServer()
+	s.AddRoute(http.MethodGet, "/ws", func(ctx *Context) {
+		_, err := ctx.UpgradeWebSocket()
+		assert.EqualError(t, err, "web: 不是 WebSocket 握手请求")
+	})
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestWebSocket_closeFrame(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	ws := newWebSocket(server, bufio.NewReader(server), false, 0)
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := ws.ReadMessage()
+		done <- err
+		// net.Pipe 没有缓冲，继续读掉客户端回复的关闭帧
+		_, _ = io.Copy(io.Discard, server)
+	}()
+	cws := newWebSocket(client, bufio.NewReader(client), true, 0)
+	payload := binary.BigEndian.AppendUint16(nil, 1004)
+	writeRawFrame(t, cws, true, CloseMessage, true, payload)
+	// 服务端以协议错误回复
+	_, _, err := cws.ReadMessage()
+	assert.Equal(t, CloseProtocolError, err.(*CloseError).Code)
+	assert.Equal(t, CloseProtocolError, (<-done).(*CloseError).Code)
+}
+
+func TestWebSocket_frameTooLarge(t *testing.T) {
+	assert.PanicsWithValue(t, "web: WebSocket 消息的大小限制必须大于 0 [0]", func() {
+		WebSocketMaxMessageSize(0)
+	})
+
+	server, client := net.Pipe()
+	defer client.Close()
+	ws := newWebSocket(server, bufio.NewReader(server), false, 0)
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := ws.ReadMessage()
+		done <- err
+		_, _ = io.Copy(io.Discard, server)
+	}()
+	// 帧头声明了 1TB 的长度，服务端不能按照这个长度分配内存
+	header := []byte{0x80 | BinaryMessage, 0x80 | 127}
+	header = binary.BigEndian.AppendUint64(header, 1<<40)
+	_, err := client.Write(header)
+	require.NoError(t, err)
+
+	cws := newWebSocket(client, bufio.NewReader(client), true, 0)
+	_, _, err = cws.ReadMessage()
+	assert.Equal(t, CloseMessageTooBig, err.(*CloseError).Code)
+	assert.Equal(t, CloseMessageTooBig, (<-done).(*CloseError).Code)
+}